@@ -0,0 +1,220 @@
+package bento
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// usStates are the two letter codes accepted for Address.State: the states,
+// the District of Columbia, the territories and the military "states".
+var usStates = map[string]bool{
+	"AL": true, "AK": true, "AZ": true, "AR": true, "CA": true, "CO": true,
+	"CT": true, "DE": true, "FL": true, "GA": true, "HI": true, "ID": true,
+	"IL": true, "IN": true, "IA": true, "KS": true, "KY": true, "LA": true,
+	"ME": true, "MD": true, "MA": true, "MI": true, "MN": true, "MS": true,
+	"MO": true, "MT": true, "NE": true, "NV": true, "NH": true, "NJ": true,
+	"NM": true, "NY": true, "NC": true, "ND": true, "OH": true, "OK": true,
+	"OR": true, "PA": true, "RI": true, "SC": true, "SD": true, "TN": true,
+	"TX": true, "UT": true, "VT": true, "VA": true, "WA": true, "WV": true,
+	"WI": true, "WY": true,
+	"DC": true,
+	"AS": true, "GU": true, "MP": true, "PR": true, "VI": true, "UM": true,
+	"AA": true, "AE": true, "AP": true,
+}
+
+// zipPattern matches a five digit ZIP code or a ZIP+4 code.
+var zipPattern = regexp.MustCompile(`^\d{5}(-\d{4})?$`)
+
+// Validate checks that the address has a street and city, that State is a
+// two letter US state or territory code such as "CA", and that ZipCode is a
+// five digit or ZIP+4 code such as "94105" or "94105-1234". Addresses are
+// validated before they are sent.
+func (address *Address) Validate() error {
+	var problems []string
+	if strings.TrimSpace(address.Street) == "" {
+		problems = append(problems, "street is required")
+	}
+	if strings.TrimSpace(address.City) == "" {
+		problems = append(problems, "city is required")
+	}
+	if !usStates[address.State] {
+		problems = append(problems, fmt.Sprintf("state %q is not a US state code", address.State))
+	}
+	if !zipPattern.MatchString(address.ZipCode) {
+		problems = append(problems, fmt.Sprintf("zip code %q is not a 5 digit or ZIP+4 code", address.ZipCode))
+	}
+	if len(problems) > 0 {
+		return errors.New(fmt.Sprintf("Invalid address: %s", strings.Join(problems, ", ")))
+	}
+	return nil
+}
+
+// GetBusinessAddresses returns the business's addresses, including inactive
+// ones.
+func (session *Session) GetBusinessAddresses() ([]Address, error) {
+	return session.GetBusinessAddressesContext(context.Background())
+}
+
+// GetBusinessAddressesContext is like GetBusinessAddresses but uses ctx for
+// the request.
+func (session *Session) GetBusinessAddressesContext(ctx context.Context) ([]Address, error) {
+	return session.getAddresses(ctx, "/businesses/me/addresses")
+}
+
+// NewBusinessAddress adds an active address to the business and returns
+// it.
+func (session *Session) NewBusinessAddress(address *Address) (*Address, error) {
+	return session.NewBusinessAddressContext(context.Background(), address)
+}
+
+// NewBusinessAddressContext is like NewBusinessAddress but uses ctx for the
+// request.
+func (session *Session) NewBusinessAddressContext(ctx context.Context, address *Address) (*Address, error) {
+	return session.sendAddress(ctx, "POST", "/businesses/me/addresses", BUSINESS_ADDRESS, address)
+}
+
+// UpdateBusinessAddress saves changes to the business address with
+// address.Id and returns it. address.Active is ignored; use
+// DeactivateBusinessAddress to deactivate an address.
+func (session *Session) UpdateBusinessAddress(address *Address) (*Address, error) {
+	return session.UpdateBusinessAddressContext(context.Background(), address)
+}
+
+// UpdateBusinessAddressContext is like UpdateBusinessAddress but uses ctx
+// for the request.
+func (session *Session) UpdateBusinessAddressContext(ctx context.Context, address *Address) (*Address, error) {
+	endpoint := fmt.Sprintf("/businesses/me/addresses/%d", address.Id)
+	return session.sendAddress(ctx, "PUT", endpoint, BUSINESS_ADDRESS, address)
+}
+
+// DeactivateBusinessAddress deactivates the business address with the given
+// ID and returns it.
+func (session *Session) DeactivateBusinessAddress(addressId int64) (*Address, error) {
+	return session.DeactivateBusinessAddressContext(context.Background(), addressId)
+}
+
+// DeactivateBusinessAddressContext is like DeactivateBusinessAddress but
+// uses ctx for the request.
+func (session *Session) DeactivateBusinessAddressContext(ctx context.Context, addressId int64) (*Address, error) {
+	return session.deactivateAddress(ctx, fmt.Sprintf("/businesses/me/addresses/%d", addressId))
+}
+
+func (user *User) addressesEndpoint() string {
+	return fmt.Sprintf("/users/%d/addresses", user.UserId)
+}
+
+// GetAddresses returns the user's addresses, including inactive ones.
+func (user *User) GetAddresses() ([]Address, error) {
+	return user.GetAddressesContext(context.Background())
+}
+
+// GetAddressesContext is like GetAddresses but uses ctx for the request.
+func (user *User) GetAddressesContext(ctx context.Context) ([]Address, error) {
+	return user.session.getAddresses(ctx, user.addressesEndpoint())
+}
+
+// NewAddress adds an active address to the user and returns it.
+func (user *User) NewAddress(address *Address) (*Address, error) {
+	return user.NewAddressContext(context.Background(), address)
+}
+
+// NewAddressContext is like NewAddress but uses ctx for the request.
+func (user *User) NewAddressContext(ctx context.Context, address *Address) (*Address, error) {
+	return user.session.sendAddress(ctx, "POST", user.addressesEndpoint(), USER_ADDRESS, address)
+}
+
+// UpdateAddress saves changes to the user's address with address.Id and
+// returns it. address.Active is ignored; use DeactivateAddress to
+// deactivate an address.
+func (user *User) UpdateAddress(address *Address) (*Address, error) {
+	return user.UpdateAddressContext(context.Background(), address)
+}
+
+// UpdateAddressContext is like UpdateAddress but uses ctx for the request.
+func (user *User) UpdateAddressContext(ctx context.Context, address *Address) (*Address, error) {
+	endpoint := fmt.Sprintf("%s/%d", user.addressesEndpoint(), address.Id)
+	return user.session.sendAddress(ctx, "PUT", endpoint, USER_ADDRESS, address)
+}
+
+// DeactivateAddress deactivates the user's address with the given ID and
+// returns it.
+func (user *User) DeactivateAddress(addressId int64) (*Address, error) {
+	return user.DeactivateAddressContext(context.Background(), addressId)
+}
+
+// DeactivateAddressContext is like DeactivateAddress but uses ctx for the
+// request.
+func (user *User) DeactivateAddressContext(ctx context.Context, addressId int64) (*Address, error) {
+	return user.session.deactivateAddress(ctx, fmt.Sprintf("%s/%d", user.addressesEndpoint(), addressId))
+}
+
+func (session *Session) getAddresses(ctx context.Context, endpoint string) ([]Address, error) {
+	bs, err := session.call(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var addresses []Address
+	err = json.Unmarshal(bs, &addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
+// addressBody is the body of a request to create or update an address.
+// Active replaces the address's own field, so that it can be left out of
+// updates.
+type addressBody struct {
+	*Address
+	Active *bool `json:"active,omitempty"`
+}
+
+// sendAddress validates address and sends a copy of it, with its type set
+// to addressType, to endpoint. New addresses are sent as active, and
+// updates leave out Active so they don't change it.
+func (session *Session) sendAddress(ctx context.Context, method, endpoint string, addressType AddressType, address *Address) (*Address, error) {
+	err := address.Validate()
+	if err != nil {
+		return nil, err
+	}
+	newAddress := *address
+	newAddress.AddressType = addressType
+	body := addressBody{Address: &newAddress}
+	if method == "POST" {
+		active := true
+		body.Active = &active
+	}
+	bs, err := session.call(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+
+	var addressResp Address
+	err = json.Unmarshal(bs, &addressResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &addressResp, nil
+}
+
+func (session *Session) deactivateAddress(ctx context.Context, endpoint string) (*Address, error) {
+	bs, err := session.call(ctx, "DELETE", endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var address Address
+	err = json.Unmarshal(bs, &address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &address, nil
+}
@@ -0,0 +1,122 @@
+package bento
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func validAddress() *Address {
+	return &Address{Street: "123 Main Street", City: "San Francisco", State: "CA", ZipCode: "94123"}
+}
+
+func TestAddressValidate(t *testing.T) {
+	t.Log("TestAddressValidate")
+	if err := validAddress().Validate(); err != nil {
+		t.Errorf("Expected a valid address, got %v", err)
+	}
+	zipPlus4 := validAddress()
+	zipPlus4.ZipCode = "94123-4567"
+	zipPlus4.State = "DC"
+	if err := zipPlus4.Validate(); err != nil {
+		t.Errorf("Expected a valid address, got %v", err)
+	}
+
+	invalid := map[string]func(*Address){
+		"no street":       func(a *Address) { a.Street = " " },
+		"no city":         func(a *Address) { a.City = "" },
+		"no state":        func(a *Address) { a.State = "" },
+		"state name":      func(a *Address) { a.State = "California" },
+		"lowercase state": func(a *Address) { a.State = "ca" },
+		"unknown state":   func(a *Address) { a.State = "ZZ" },
+		"no zip":          func(a *Address) { a.ZipCode = "" },
+		"short zip":       func(a *Address) { a.ZipCode = "9412" },
+		"letters in zip":  func(a *Address) { a.ZipCode = "9412A" },
+		"bad zip+4":       func(a *Address) { a.ZipCode = "94123-45" },
+	}
+	for name, change := range invalid {
+		address := validAddress()
+		change(address)
+		if err := address.Validate(); err == nil {
+			t.Errorf("Expected an address with %s to be invalid", name)
+		}
+	}
+}
+
+func TestInvalidAddressNotSent(t *testing.T) {
+	t.Log("TestInvalidAddressNotSent")
+	tbs := &TestSession{}
+	session := &Session{requester: testRequest(tbs)}
+	user := &User{UserId: 12345, session: session}
+	card := &Card{CardId: 12345, session: session}
+	address := validAddress()
+	address.ZipCode = "ABCDE"
+
+	if _, err := session.NewBusinessAddress(address); err == nil {
+		t.Error("Expected NewBusinessAddress to reject the address")
+	}
+	if _, err := session.UpdateBusinessAddress(address); err == nil {
+		t.Error("Expected UpdateBusinessAddress to reject the address")
+	}
+	if _, err := user.NewAddress(address); err == nil {
+		t.Error("Expected NewAddress to reject the address")
+	}
+	if _, err := user.UpdateAddress(address); err == nil {
+		t.Error("Expected UpdateAddress to reject the address")
+	}
+	if _, err := card.SetBillingAddress(address); err == nil {
+		t.Error("Expected SetBillingAddress to reject the address")
+	}
+	if _, err := card.UpdateBillingAddress(address); err == nil {
+		t.Error("Expected UpdateBillingAddress to reject the address")
+	}
+	if tbs.endpoint != "" {
+		t.Errorf("Expected no requests, got %s %s", tbs.method, tbs.endpoint)
+	}
+}
+
+func TestAddressEndpoints(t *testing.T) {
+	t.Log("TestAddressEndpoints")
+	tbs := &TestSession{}
+	session := &Session{requester: testRequestFailures(tbs)}
+	user := &User{UserId: 777, session: session}
+	address := validAddress()
+	address.Id = 55
+
+	session.NewBusinessAddress(address)
+	if tbs.method != "POST" || tbs.endpoint != "/businesses/me/addresses" ||
+		tbs.args.(addressBody).AddressType != BUSINESS_ADDRESS {
+		t.Errorf("Unexpected request: %s %s %+v", tbs.method, tbs.endpoint, tbs.args)
+	}
+	if bs, _ := json.Marshal(tbs.args); !strings.Contains(string(bs), `"active":true`) {
+		t.Errorf("Expected a new address to be sent as active: %s", bs)
+	}
+	session.UpdateBusinessAddress(address)
+	if tbs.method != "PUT" || tbs.endpoint != "/businesses/me/addresses/55" {
+		t.Errorf("Unexpected request: %s %s", tbs.method, tbs.endpoint)
+	}
+	if bs, _ := json.Marshal(tbs.args); strings.Contains(string(bs), `"active"`) {
+		t.Errorf("Expected an update to leave out active: %s", bs)
+	}
+	session.DeactivateBusinessAddress(55)
+	if tbs.method != "DELETE" || tbs.endpoint != "/businesses/me/addresses/55" {
+		t.Errorf("Unexpected request: %s %s", tbs.method, tbs.endpoint)
+	}
+
+	user.NewAddress(address)
+	if tbs.method != "POST" || tbs.endpoint != "/users/777/addresses" ||
+		tbs.args.(addressBody).AddressType != USER_ADDRESS {
+		t.Errorf("Unexpected request: %s %s %+v", tbs.method, tbs.endpoint, tbs.args)
+	}
+	if address.AddressType != "" {
+		t.Error(`Expected the caller's address not to be modified`)
+	}
+	user.GetAddresses()
+	if tbs.method != "GET" || tbs.endpoint != "/users/777/addresses" {
+		t.Errorf("Unexpected request: %s %s", tbs.method, tbs.endpoint)
+	}
+	user.DeactivateAddress(55)
+	if tbs.method != "DELETE" || tbs.endpoint != "/users/777/addresses/55" {
+		t.Errorf("Unexpected request: %s %s", tbs.method, tbs.endpoint)
+	}
+}
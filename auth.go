@@ -0,0 +1,95 @@
+package bento
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// CredentialsFunc returns the access and secret keys a session logs in with.
+type CredentialsFunc func(ctx context.Context) (accessKey, secretKey string, err error)
+
+func staticCredentials(accessKey, secretKey string) CredentialsFunc {
+	return func(ctx context.Context) (string, string, error) {
+		return accessKey, secretKey, nil
+	}
+}
+
+// ReauthEvent describes a re-login performed by a session after the API
+// rejected its authorization token.
+type ReauthEvent struct {
+	// Method and Endpoint identify the request that was rejected.
+	Method   string
+	Endpoint string
+	// Time is when the re-login finished.
+	Time time.Time
+	// Err is nil if the session obtained a new token, otherwise the reason
+	// it could not.
+	Err error
+}
+
+// WithCredentials makes the session ask fn for keys whenever its token
+// expires and it has to log in again. Without this option the session logs in
+// again with the keys it was created with. This is useful when keys are
+// rotated while the session is alive.
+func WithCredentials(fn CredentialsFunc) Option {
+	return func(session *Session) {
+		session.credentials = fn
+	}
+}
+
+// WithReauthHook registers hook to be called after every re-login, whether
+// or not it succeeded.
+func WithReauthHook(hook func(ReauthEvent)) Option {
+	return func(session *Session) {
+		session.reauthHook = hook
+	}
+}
+
+// reauthenticate logs in again after a request sent with staleToken was
+// rejected with 401 Unauthorized.
+func (session *Session) reauthenticate(ctx context.Context, method, endpoint, staleToken string) error {
+	err := session.loginMu.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer session.loginMu.unlock()
+	if session.token() != staleToken {
+		// Another request already logged in again while we waited.
+		return nil
+	}
+
+	err = session.relogin(ctx)
+	if err != nil {
+		session.log(ctx, slog.LevelError, "Unable to log in again",
+			slog.String("method", method),
+			slog.String("endpoint", endpoint),
+			slog.Any("error", err))
+	} else {
+		session.log(ctx, slog.LevelInfo, "Logged in again after token expired",
+			slog.String("method", method),
+			slog.String("endpoint", endpoint))
+	}
+	if session.reauthHook != nil {
+		session.reauthHook(ReauthEvent{
+			Method:   method,
+			Endpoint: endpoint,
+			Time:     time.Now(),
+			Err:      err,
+		})
+	}
+	return err
+}
+
+func (session *Session) relogin(ctx context.Context) error {
+	accessKey, secretKey, err := session.credentials(ctx)
+	if err != nil {
+		return fmt.Errorf("Unable to get credentials to log in again: %w", err)
+	}
+	err = session.login(ctx, accessKey, secretKey)
+	if err != nil {
+		return fmt.Errorf("Unable to log in again: %w", err)
+	}
+	return nil
+}
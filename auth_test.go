@@ -0,0 +1,144 @@
+package bento
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newExpiringServer returns a server whose tokens are only valid until the
+// next login. expire invalidates the current token.
+func newExpiringServer(t *testing.T) (server *httptest.Server, logins *int, expire func()) {
+	logins = new(int)
+	valid := ""
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sessions":
+			if r.Header.Get("Authorization") != "" {
+				t.Error("Expected login request to carry no Authorization header")
+			}
+			*logins++
+			valid = fmt.Sprintf("token-%d", *logins)
+			w.Header().Set("Authorization", valid)
+			w.Write([]byte(`{"apiApplicationId": 1}`))
+		case "/cards":
+			if r.Header.Get("Authorization") != valid {
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"message": "Unauthorized", "error": "token expired"}`))
+				return
+			}
+			w.Write([]byte("[" + SampleCard + "]"))
+		}
+	}))
+	return server, logins, func() { valid = "" }
+}
+
+func TestReauthenticate(t *testing.T) {
+	t.Log("TestReauthenticate")
+	server, logins, expire := newExpiringServer(t)
+	defer server.Close()
+
+	var events []ReauthEvent
+	session, err := GetTestSession("access", "secret",
+		WithBaseURL(server.URL),
+		WithReauthHook(func(e ReauthEvent) { events = append(events, e) }))
+	if err != nil {
+		t.Fatalf("Failed to create session: %s", err)
+	}
+
+	expire()
+	_, err = session.GetCards()
+	if err != nil {
+		t.Fatalf("Expected GetCards to succeed after re-login: %s", err)
+	}
+	if *logins != 2 {
+		t.Errorf("Expected 2 logins, got %d", *logins)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 reauth event, got %d", len(events))
+	}
+	if events[0].Method != "GET" || events[0].Endpoint != "/cards" || events[0].Err != nil {
+		t.Errorf("Unexpected reauth event: %+v", events[0])
+	}
+}
+
+func TestReauthenticateCredentialsFailure(t *testing.T) {
+	t.Log("TestReauthenticateCredentialsFailure")
+	server, _, expire := newExpiringServer(t)
+	defer server.Close()
+
+	keysRevoked := errors.New("keys revoked")
+	var events []ReauthEvent
+	session, err := GetTestSession("access", "secret",
+		WithBaseURL(server.URL),
+		WithCredentials(func(ctx context.Context) (string, string, error) {
+			return "", "", keysRevoked
+		}),
+		WithReauthHook(func(e ReauthEvent) { events = append(events, e) }))
+	if err != nil {
+		t.Fatalf("Failed to create session: %s", err)
+	}
+
+	expire()
+	_, err = session.GetCards()
+	if !errors.Is(err, keysRevoked) {
+		t.Errorf("Expected GetCards to fail with the credentials error, got: %v", err)
+	}
+	if len(events) != 1 || !errors.Is(events[0].Err, keysRevoked) {
+		t.Errorf("Expected a failed reauth event, got: %+v", events)
+	}
+}
+
+func TestReauthenticateWaitRespectsContext(t *testing.T) {
+	t.Log("TestReauthenticateWaitRespectsContext")
+	var mu sync.Mutex
+	logins := 0
+	loggingIn := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sessions" {
+			mu.Lock()
+			logins++
+			first := logins == 1
+			mu.Unlock()
+			if !first {
+				// The re-login hangs until the test is done.
+				close(loggingIn)
+				<-release
+			}
+			w.Header().Set("Authorization", "token")
+			w.Write([]byte(`{"apiApplicationId": 1}`))
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"message": "Unauthorized", "error": "token expired"}`))
+	}))
+	defer server.Close()
+	defer close(release)
+
+	session, err := GetTestSession("access", "secret", WithBaseURL(server.URL), WithRetryPolicy(NoRetries))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One caller without a deadline gets stuck logging in again.
+	go session.GetCards()
+	<-loggingIn
+
+	// Another caller needing to log in gives up at its own deadline.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = session.GetCardsContext(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected to give up after 50ms, took %s", elapsed)
+	}
+}
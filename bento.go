@@ -13,32 +13,86 @@ For production:
 For the sandbox:
 	session, err := bento.GetTestSession("myTestAccessKey", "myTestSecretKey")
 
+Both accept Options that configure how the session talks to the API. The
+session reuses a single *http.Client for all of its requests:
+	session, err := bento.GetProductionSession("myAccessKey", "mySecretKey",
+		bento.WithHTTPClient(&http.Client{Timeout: 30 * time.Second}),
+		bento.WithUserAgent("my-service/1.0"))
+
 Once you have a session, you can begin doing things like getting and updating
 cards and other resources associated with your bento account.
 
+Every method that talks to the API has a Context variant, such as
+GetCardsContext or Card.PutContext, which uses the given context.Context to
+cancel the underlying HTTP request or bound it with a deadline.
+
 See the Session object's methods to see the types of objects you can interact
 with. This is a good starting point from which you can begin to understand the
 other types provided in this package.
+
+Testing
+
+Package github.com/knusbaum/bento-go/bentotest provides an in-memory fake of
+the API, for testing code that uses a *Session without the sandbox.
 */
 package bento
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"bytes"
 	"errors"
 	"encoding/json"
+	"io"
 	"io/ioutil"
 	"log"
+	"log/slog"
+	"sync"
+	"time"
 )
 
 // Session provides the entry point to interact with the API.
 // Created with GetProductionSession and GetTestSession.
+//
+// A Session is safe for concurrent use by multiple goroutines, including
+// calls to SetLogger and ClearLogger while requests are in flight, and one
+// Session should be shared rather than creating one per goroutine. The
+// objects it returns, such as *Card, are plain values and must not be
+// modified by one goroutine while another uses them.
 type Session struct {
 	apiUri string
+
+	// mu guards authorization, application, logger and middleware.
+	// loginMu serializes logins so that concurrent requests that find the
+	// token expired only log in once. Requests waiting on it give up when
+	// their own context is done.
+	mu sync.RWMutex
+	loginMu ctxMutex
 	authorization string
-	requester func(*Session, string, string, interface{}) ([]byte, error)
-	logger *log.Logger
-}
+	application ApiApplication
+	client *http.Client
+	userAgent string
+	credentials CredentialsFunc
+	reauthHook func(ReauthEvent)
+	retryPolicy RetryPolicy
+	rateLimiter *tokenBucket
+	inFlight chan struct{}
+	limiterStats limiterStats
+	middleware []Middleware
+	requester requestFunc
+	logger *slog.Logger
+	detectConflicts bool
+	refreshInPlace bool
+
+	// categoriesMu guards categories, the cached category catalog, and is
+	// held while it is fetched.
+	categoriesMu ctxMutex
+	categories Categories
+}
+
+// requestFunc performs a single API call against endpoint and returns the
+// raw response body. It is swapped out in tests.
+type requestFunc func(ctx context.Context, session *Session, method, endpoint string, args interface{}) ([]byte, error)
 
 // AddressType can be "BUSINESS_ADDRESS" or "USER_ADDRESS"
 type AddressType string
@@ -112,6 +166,7 @@ type User struct {
 	Deleted bool      `json:"deleted"`
 	Created int64     `json:"created"`
 	BentoType string  `json:"bentoType,omitempty"`
+	session *Session  `json:"-"`
 }
 
 type Category struct {
@@ -171,6 +226,8 @@ type PanAndCvv struct {
 	Cvv string `json:"cvv,omitempty"`
 }
 
+// BentoError is the error object the API returns in the body of a failed
+// response. It is usually wrapped in an *APIError.
 type BentoError struct {
 	Message string
 	BentoError string `json:"error"`
@@ -198,147 +255,287 @@ func checkError(bs []byte) error {
 var sandboxUri string = "https://sandbox-api.bentoforbusiness.com/api"
 var productionUri string = "https://api.bentoforbusiness.com"
 
-func GetProductionSession(accessKey, secretKey string) (*Session, error) {
-	return getSession(productionUri, accessKey, secretKey)
+// GetProductionSession logs in to the production API. Any opts are applied
+// to the session before logging in.
+func GetProductionSession(accessKey, secretKey string, opts ...Option) (*Session, error) {
+	return GetProductionSessionContext(context.Background(), accessKey, secretKey, opts...)
+}
+
+// GetProductionSessionContext is like GetProductionSession but uses ctx
+// for the login request.
+func GetProductionSessionContext(ctx context.Context, accessKey, secretKey string, opts ...Option) (*Session, error) {
+	return getSession(ctx, productionUri, accessKey, secretKey, opts)
 }
 
-func GetTestSession(accessKey, secretKey string) (*Session, error) {
-	return getSession(sandboxUri, accessKey, secretKey)
+// GetTestSession logs in to the sandbox API. Any opts are applied to the
+// session before logging in.
+func GetTestSession(accessKey, secretKey string, opts ...Option) (*Session, error) {
+	return GetTestSessionContext(context.Background(), accessKey, secretKey, opts...)
 }
 
-func getSession(apiUri, accessKey, secretKey string) (*Session, error) {
+// GetTestSessionContext is like GetTestSession but uses ctx for the login
+// request.
+func GetTestSessionContext(ctx context.Context, accessKey, secretKey string, opts ...Option) (*Session, error) {
+	return getSession(ctx, sandboxUri, accessKey, secretKey, opts)
+}
 
-	client := &http.Client{}
+func getSession(ctx context.Context, apiUri, accessKey, secretKey string, opts []Option) (*Session, error) {
+	session := &Session{
+		apiUri: apiUri,
+		client: &http.Client{},
+		userAgent: defaultUserAgent,
+		credentials: staticCredentials(accessKey, secretKey),
+		retryPolicy: DefaultRetryPolicy,
+		requester: doRequest,
+	}
+	for _, opt := range opts {
+		opt(session)
+	}
 
+	err := session.login(ctx, accessKey, secretKey)
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// login exchanges the access and secret keys for an authorization token and
+// stores it on the session.
+func (session *Session) login(ctx context.Context, accessKey, secretKey string) error {
 	bs, err := json.Marshal(
 		map[string]string{
 			"accessKey": accessKey,
 			"secretKey": secretKey})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	req, err := http.NewRequest("POST",
-		fmt.Sprintf("%s/sessions", apiUri),
-		bytes.NewReader(bs))
+	req, err := session.newRequest(ctx, "POST", "/sessions", "application/json", bs)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	req.Header.Del("Authorization")
 
-	req.Header.Add("Content-Type", "application\\json")
-	req.Header.Add("Accept", "*/*")
-	resp, err := client.Do(req)
+	resp, err := session.httpClient().Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	if resp.StatusCode >= 400 {
+		return session.newAPIError("POST", "/sessions", resp, body)
 	}
 
 	if !json.Valid(body) {
-		return nil, errors.New(
+		return errors.New(
 			fmt.Sprintf("Invalid json response: [%s]", string(body)))
 	}
 
 	auth, ok := resp.Header["Authorization"]
 	if !ok {
-		return nil, errors.New("Server did not return an authorization token.")
+		return errors.New("Server did not return an authorization token.")
 	}
 
 	app := &ApiApplication{}
 	err = json.Unmarshal(body, app)
 	if err != nil {
-		return nil, errors.New(fmt.Sprintf("Error unmarshalling: %s", err))
+		return errors.New(fmt.Sprintf("Error unmarshalling: %s", err))
 	}
 
-	session := &Session{
-		apiUri: apiUri,
-		authorization: auth[0],
-		requester: doRequest,
-		logger: log.New(ioutil.Discard, "", 0),
+	session.mu.Lock()
+	session.authorization = auth[0]
+	session.application = *app
+	session.mu.Unlock()
+	return nil
+}
+
+// httpClient returns the client set on the session, or http.DefaultClient
+// for sessions that were not created by getSession.
+func (session *Session) httpClient() *http.Client {
+	if session.client == nil {
+		return http.DefaultClient
 	}
-	return session, nil
+	return session.client
+}
+
+// newRequest builds a request for endpoint with the headers common to every
+// call. body may be nil, in which case contentType is ignored.
+func (session *Session) newRequest(ctx context.Context, method, endpoint, contentType string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method,
+		fmt.Sprintf("%s%s", session.apiUri, endpoint),
+		reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if body != nil {
+		req.Header.Add("Content-Type", contentType)
+	}
+	req.Header.Add("Accept", "*/*")
+	if session.userAgent != "" {
+		req.Header.Set("User-Agent", session.userAgent)
+	}
+	for key, values := range requestHeader(ctx) {
+		req.Header[key] = append([]string(nil), values...)
+	}
+	if token := session.token(); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	return req, nil
 }
 
 // SetLogger sets a *log.Logger on the session. All requests and responses will
-// be logged to that logger.
+// be logged to that logger, one line each, with secrets redacted as described
+// for SetStructuredLogger.
 func (session *Session) SetLogger(logger *log.Logger) {
-	session.logger = logger
+	session.SetStructuredLogger(newLogLogger(logger))
 }
 
-// ClearLogger clears any logger set by SetLogger from session. After this call
+// ClearLogger clears any logger set by SetLogger or SetStructuredLogger from
+// session. After this call
 // completes, nothing will be logged by the session.
 func (session *Session) ClearLogger() {
-	session.logger = log.New(ioutil.Discard, "", 0)
-}
-
-func doRequest(session *Session, method, endpoint string, args interface{}) ([]byte, error) {
-	client := &http.Client{}
-
-	var err error
-	var req *http.Request
-	if args != nil {
-		bs, err := json.Marshal(args)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.logger = nil
+}
+
+// Application returns the API application the session logged in as. It is
+// updated if the session logs in again.
+func (session *Session) Application() ApiApplication {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+	return session.application
+}
+
+// token returns the session's current authorization token.
+func (session *Session) token() string {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+	return session.authorization
+}
+
+func doRequest(ctx context.Context, session *Session, method, endpoint string, args interface{}) ([]byte, error) {
+	var bs []byte
+	contentType := "application/json"
+	switch args := args.(type) {
+	case nil:
+	case rawBody:
+		bs, contentType = args.data, args.contentType
+	default:
+		var err error
+		bs, err = json.Marshal(args)
 		if err != nil {
 			return nil, err
 		}
+	}
+
+	token := session.token()
+	resp, body, err := session.roundTripWithRetry(ctx, method, endpoint, contentType, bs)
+	if err != nil {
+		return nil, err
+	}
 
-		req, err = http.NewRequest(method,
-			fmt.Sprintf("%s%s", session.apiUri, endpoint),
-			bytes.NewReader(bs))
+	if resp.StatusCode == http.StatusUnauthorized && session.credentials != nil {
+		err = session.reauthenticate(ctx, method, endpoint, token)
 		if err != nil {
 			return nil, err
 		}
-
-		req.Header.Add("Content-Type", "application\\json")
-		req.Header.Add("Accept", "*/*")
-		req.Header.Add("Authorization", session.authorization)
-		session.logger.Printf("Sending request: [method: %s] [uri: %s] body: %s",
-			method, fmt.Sprintf("%s%s", session.apiUri, endpoint), string(bs))
-	} else {
-		req, err = http.NewRequest(method,
-			fmt.Sprintf("%s%s", session.apiUri, endpoint),
-			nil)
+		resp, body, err = session.roundTripWithRetry(ctx, method, endpoint, contentType, bs)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		req.Header.Add("Accept", "*/*")
-		req.Header.Add("Authorization", session.authorization)
-		session.logger.Printf("Sending request: [method: %s] [uri: %s]",
-			method, fmt.Sprintf("%s%s", session.apiUri, endpoint))
+	if resp.StatusCode >= 400 {
+		return nil, session.newAPIError(method, endpoint, resp, body)
 	}
 
-	resp, err := client.Do(req)
+	if rawResponse(ctx) {
+		return body, nil
+	}
+
+	if !json.Valid(body) {
+		return nil, errors.New(
+			fmt.Sprintf("Server returned non-json value: [%s]", Redact(body)))
+	}
+
+	if checkError(body) != nil {
+		return nil, session.newAPIError(method, endpoint, resp, body)
+	}
+
+	return body, nil
+}
+
+// roundTrip sends a single request and reads the whole response body. The
+// returned response's body is already closed. attempt is only used for
+// logging.
+func (session *Session) roundTrip(ctx context.Context, method, endpoint, contentType string, bs []byte, attempt int) (*http.Response, []byte, error) {
+	req, err := session.newRequest(ctx, method, endpoint, contentType, bs)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := ioutil.ReadAll(resp.Body)
-	session.logger.Printf("Received response: %s", string(body))
+	release, err := session.acquire(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	defer release()
 
-	if !json.Valid(body) {
-		return nil, errors.New(
-			fmt.Sprintf("Server returned non-json value: [%s]", string(body)))
+	session.log(ctx, slog.LevelDebug, "Sending request",
+		slog.String("method", method),
+		slog.String("endpoint", endpoint),
+		slog.Int("attempt", attempt),
+		slog.String("body", loggedBody(contentType, bs)))
+
+	start := time.Now()
+	resp, err := session.httpClient().Do(req)
+	if err != nil {
+		session.log(ctx, slog.LevelWarn, "Request failed",
+			slog.String("method", method),
+			slog.String("endpoint", endpoint),
+			slog.Int("attempt", attempt),
+			slog.Duration("latency", time.Since(start)),
+			slog.Any("error", err))
+		return nil, nil, err
 	}
+	defer resp.Body.Close()
 
-	err = checkError(body)
+	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return body, nil
+	level := slog.LevelDebug
+	if resp.StatusCode >= 400 {
+		level = slog.LevelWarn
+	}
+	session.log(ctx, level, "Received response",
+		slog.String("method", method),
+		slog.String("endpoint", endpoint),
+		slog.Int("attempt", attempt),
+		slog.Int("status", resp.StatusCode),
+		slog.Duration("latency", time.Since(start)),
+		slog.String("body", loggedBody(resp.Header.Get("Content-Type"), body)))
+	return resp, body, nil
 }
 
 func (session *Session) GetBusiness() (*Business, error) {
-	bs, err := session.requester(session, "GET", "/businesses/me", nil)
+	return session.GetBusinessContext(context.Background())
+}
+
+// GetBusinessContext is like GetBusiness but uses ctx for the request.
+func (session *Session) GetBusinessContext(ctx context.Context) (*Business, error) {
+	bs, err := session.call(ctx, "GET", "/businesses/me", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -353,7 +550,12 @@ func (session *Session) GetBusiness() (*Business, error) {
 }
 
 func (session *Session) GetCards() ([]Card, error) {
-	bs, err := session.requester(session, "GET", "/cards", nil)
+	return session.GetCardsContext(context.Background())
+}
+
+// GetCardsContext is like GetCards but uses ctx for the request.
+func (session *Session) GetCardsContext(ctx context.Context) ([]Card, error) {
+	bs, err := session.call(ctx, "GET", "/cards", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -372,7 +574,12 @@ func (session *Session) GetCards() ([]Card, error) {
 }
 
 func (session *Session) GetCard(cardId int64) (*Card, error) {
-	bs, err := session.requester(session, "GET", fmt.Sprintf("/cards/%d", cardId), nil)
+	return session.GetCardContext(context.Background(), cardId)
+}
+
+// GetCardContext is like GetCard but uses ctx for the request.
+func (session *Session) GetCardContext(ctx context.Context, cardId int64) (*Card, error) {
+	bs, err := session.call(ctx, "GET", fmt.Sprintf("/cards/%d", cardId), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -387,30 +594,43 @@ func (session *Session) GetCard(cardId int64) (*Card, error) {
 	return &card, nil
 }
 
-func (session *Session) NewCard(cardType CardType, alias string) (*Card, error) {
-	bs, err := session.requester(session, "POST", "/cards",
-		map[string]interface{}{
-			"type": cardType,
-			"alias": alias,
-			"virtualCard": false,
-			"lastFour": 3215,
-		})
-	if err != nil {
-		return nil, err
-	}
+// NewCard creates a card of the given type, configured by opts. For
+// example, a virtual card for meals limited to $500 a month:
+//
+//	card, err := session.NewCard(bento.CATEGORY_CARD, "Meals",
+//		bento.Virtual(), bento.InCategory(3), bento.LimitSpending(500, bento.PERIOD_MONTH))
+//
+// The card is validated as described for CreateCard before it is sent.
+func (session *Session) NewCard(cardType CardType, alias string, opts ...CardOption) (*Card, error) {
+	return session.NewCardContext(context.Background(), cardType, alias, opts...)
+}
 
-	var cardResp Card
-	err = json.Unmarshal(bs, &cardResp)
-	if err != nil {
-		return nil, err
+// NewCardContext is like NewCard but uses ctx for the request.
+func (session *Session) NewCardContext(ctx context.Context, cardType CardType, alias string, opts ...CardOption) (*Card, error) {
+	request := NewCardRequest{Type: cardType, Alias: alias}
+	for _, opt := range opts {
+		opt(&request)
 	}
-
-	cardResp.session = session
-	return &cardResp, nil
+	return session.CreateCardContext(ctx, request)
 }
 
+// Put saves every field of the card, overwriting any changes made on the
+// server since it was fetched, unless the session was created
+// WithConflictDetection. Use Update to change only some fields.
 func (card *Card) Put() (*Card, error) {
-	bs, err := card.session.requester(card.session, "PUT", fmt.Sprintf("/cards/%d", card.CardId), card)
+	return card.PutContext(context.Background())
+}
+
+// PutContext is like Put but uses ctx for the request.
+func (card *Card) PutContext(ctx context.Context) (*Card, error) {
+	if card.session.detectConflicts {
+		return card.putChecked(ctx)
+	}
+	return card.put(ctx)
+}
+
+func (card *Card) put(ctx context.Context) (*Card, error) {
+	bs, err := card.session.call(ctx, "PUT", fmt.Sprintf("/cards/%d", card.CardId), card)
 	if err != nil {
 		return nil, err
 	}
@@ -426,7 +646,12 @@ func (card *Card) Put() (*Card, error) {
 }
 
 func (card *Card) Delete() (*Card, error) {
-	bs, err := card.session.requester(card.session, "DELETE", fmt.Sprintf("/cards/%d", card.CardId), nil)
+	return card.DeleteContext(context.Background())
+}
+
+// DeleteContext is like Delete but uses ctx for the request.
+func (card *Card) DeleteContext(ctx context.Context) (*Card, error) {
+	bs, err := card.session.call(ctx, "DELETE", fmt.Sprintf("/cards/%d", card.CardId), nil)
 	if err != nil {
 		return nil, err
 	}
@@ -441,12 +666,21 @@ func (card *Card) Delete() (*Card, error) {
 	return &cardResp, nil
 }
 
+// Activate activates a physical card, given the last four digits of its
+// number, and returns the activated card. card itself is only changed if
+// the session was created WithRefreshInPlace and activation succeeds.
 func (card *Card) Activate(lastFour string) (*Card, error) {
-	card.LastFour = lastFour
-	bs, err := card.session.requester(card.session,
+	return card.ActivateContext(context.Background(), lastFour)
+}
+
+// ActivateContext is like Activate but uses ctx for the request.
+func (card *Card) ActivateContext(ctx context.Context, lastFour string) (*Card, error) {
+	activation := *card
+	activation.LastFour = lastFour
+	bs, err := card.session.call(ctx,
 		"POST",
 		fmt.Sprintf("/cards/%d/activation", card.CardId),
-		card)
+		&activation)
 	if err != nil {
 		return nil, err
 	}
@@ -458,35 +692,62 @@ func (card *Card) Activate(lastFour string) (*Card, error) {
 	}
 
 	cardResp.session = card.session
+	card.refresh(&cardResp)
 	return &cardResp, nil
 }
 
+// TurnOn turns the card on and returns it. card itself is only changed if
+// the session was created WithRefreshInPlace and the card was turned on.
 func (card *Card) TurnOn() (*Card, error) {
-	card.Status = STATUS_TURNED_ON
-	card, err := card.Put()
-	if err != nil {
-		return nil, err
-	}
-	if card.Status != STATUS_TURNED_ON {
-		return nil, errors.New(fmt.Sprintf("Bento returned success for turn on, but card's status is: %s", card.Status))
-	}
-	return card, nil
+	return card.TurnOnContext(context.Background())
+}
+
+// TurnOnContext is like TurnOn but uses ctx for the request.
+func (card *Card) TurnOnContext(ctx context.Context) (*Card, error) {
+	return card.setStatus(ctx, STATUS_TURNED_ON, "turn on")
 }
 
+// TurnOff turns the card off and returns it. card itself is only changed
+// if the session was created WithRefreshInPlace and the card was turned off.
 func (card *Card) TurnOff() (*Card, error) {
-	card.Status = STATUS_TURNED_OFF
-	card, err := card.Put()
+	return card.TurnOffContext(context.Background())
+}
+
+// TurnOffContext is like TurnOff but uses ctx for the request.
+func (card *Card) TurnOffContext(ctx context.Context) (*Card, error) {
+	return card.setStatus(ctx, STATUS_TURNED_OFF, "turn off")
+}
+
+// setStatus saves a copy of the card with its status set to status.
+func (card *Card) setStatus(ctx context.Context, status, action string) (*Card, error) {
+	updated := *card
+	updated.Status = status
+	cardResp, err := updated.PutContext(ctx)
 	if err != nil {
 		return nil, err
 	}
-	if card.Status != STATUS_TURNED_OFF {
-		return nil, errors.New(fmt.Sprintf("Bento returned success for turn off, but card's status is: %s", card.Status))
+	if cardResp.Status != status {
+		return nil, errors.New(fmt.Sprintf("Bento returned success for %s, but card's status is: %s", action, cardResp.Status))
+	}
+	card.refresh(cardResp)
+	return cardResp, nil
+}
+
+// refresh replaces the card with updated, its state on the server, if the
+// session was created WithRefreshInPlace.
+func (card *Card) refresh(updated *Card) {
+	if card.session.refreshInPlace {
+		*card = *updated
 	}
-	return card, nil
 }
 
 func (card *Card) Reissue() (*Card, error) {
-	bs, err := card.session.requester(card.session,
+	return card.ReissueContext(context.Background())
+}
+
+// ReissueContext is like Reissue but uses ctx for the request.
+func (card *Card) ReissueContext(ctx context.Context) (*Card, error) {
+	bs, err := card.session.call(ctx,
 		"POST",
 		fmt.Sprintf("/cards/%d/reissue", card.CardId),
 		nil)
@@ -505,7 +766,12 @@ func (card *Card) Reissue() (*Card, error) {
 }
 
 func (card *Card) GetPanAndCvv() (*PanAndCvv, error) {
-	bs, err := card.session.requester(card.session,
+	return card.GetPanAndCvvContext(context.Background())
+}
+
+// GetPanAndCvvContext is like GetPanAndCvv but uses ctx for the request.
+func (card *Card) GetPanAndCvvContext(ctx context.Context) (*PanAndCvv, error) {
+	bs, err := card.session.call(ctx,
 		"GET",
 		fmt.Sprintf("/cards/%d/pan", card.CardId),
 		nil)
@@ -523,7 +789,13 @@ func (card *Card) GetPanAndCvv() (*PanAndCvv, error) {
 }
 
 func (card *Card) GetBillingAddress() (*Address, error) {
-	bs, err := card.session.requester(card.session,
+	return card.GetBillingAddressContext(context.Background())
+}
+
+// GetBillingAddressContext is like GetBillingAddress but uses ctx for the
+// request.
+func (card *Card) GetBillingAddressContext(ctx context.Context) (*Address, error) {
+	bs, err := card.session.call(ctx,
 		"GET",
 		fmt.Sprintf("/cards/%d/billingAddress", card.CardId),
 		nil)
@@ -541,12 +813,21 @@ func (card *Card) GetBillingAddress() (*Address, error) {
 }
 
 func (card *Card) SetBillingAddress(newAddress *Address) (*Address, error) {
-	bs, err := card.session.requester(card.session,
+	return card.SetBillingAddressContext(context.Background(), newAddress)
+}
+
+// SetBillingAddressContext is like SetBillingAddress but uses ctx for the
+// request.
+func (card *Card) SetBillingAddressContext(ctx context.Context, newAddress *Address) (*Address, error) {
+	err := newAddress.Validate()
+	if err != nil {
+		return nil, err
+	}
+	bs, err := card.session.call(ctx,
 		"POST",
 		fmt.Sprintf("/cards/%d/billingAddress", card.CardId),
 		newAddress)
 	if err != nil {
-		fmt.Printf("ERROR: %s\n", err)
 		return nil, err
 	}
 
@@ -560,7 +841,17 @@ func (card *Card) SetBillingAddress(newAddress *Address) (*Address, error) {
 }
 
 func (card *Card) UpdateBillingAddress(newAddress *Address) (*Address, error) {
-	bs, err := card.session.requester(card.session,
+	return card.UpdateBillingAddressContext(context.Background(), newAddress)
+}
+
+// UpdateBillingAddressContext is like UpdateBillingAddress but uses ctx for
+// the request.
+func (card *Card) UpdateBillingAddressContext(ctx context.Context, newAddress *Address) (*Address, error) {
+	err := newAddress.Validate()
+	if err != nil {
+		return nil, err
+	}
+	bs, err := card.session.call(ctx,
 		"PUT",
 		fmt.Sprintf("/cards/%d/billingAddress", card.CardId),
 		newAddress)
@@ -577,10 +868,11 @@ func (card *Card) UpdateBillingAddress(newAddress *Address) (*Address, error) {
 	return &address, nil
 }
 
-// Transactions
+// Transactions is a page of transactions. Size is the total number of
+// transactions available, of which CardTransactions holds one page.
 type Transactions struct {
 	Amount float64                 `json:"amount,omitempty"`
-	Size int                       `json:"size",omitempty"`
+	Size int                       `json:"size,omitempty"`
 	CardTransactions []Transaction `json:"cardTransactions"`
 }
 
@@ -603,18 +895,28 @@ type Transaction struct {
 	Deleted bool             `json:"deleted,omitempty"`
 	Fees float64             `json:"fees,omitempty"`
 	LedgerBalance float64    `json:"ledgerBalance,omitempty"`
-	Note string              `json:"node,omitempty"`
+	Note string              `json:"note,omitempty"`
 	SettlementDate int64     `json:"settlementDate,omitempty"`
 	Status string            `json:"status,omitempty"`
 	Tags []string            `json:"tags,omitempty"`
 	TransactionDate int64    `json:"transactionDate,omitempty"`
 	Type string              `json:"type,omitempty"`
 	Payee *Payee             `json:"payee,omitempty"`
+	session *Session         `json:"-"`
 }
 
 
+// GetTransactions returns the first page of transactions, with the API's
+// default page size. Use GetTransactionsPage or AllTransactions to see the
+// rest.
 func (session *Session) GetTransactions() (*Transactions, error) {
-	bs, err := session.requester(session, "GET", "/transactions", nil)
+	return session.GetTransactionsContext(context.Background())
+}
+
+// GetTransactionsContext is like GetTransactions but uses ctx for the
+// request.
+func (session *Session) GetTransactionsContext(ctx context.Context) (*Transactions, error) {
+	bs, err := session.call(ctx, "GET", "/transactions", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -625,5 +927,6 @@ func (session *Session) GetTransactions() (*Transactions, error) {
 		return nil, err
 	}
 
+	transaction.bind(session)
 	return &transaction, nil
 }
@@ -1,9 +1,16 @@
 package bento
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"testing"
 	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
 )
 
 var SampleBusiness string = `{
@@ -81,8 +88,8 @@ type TestSession struct {
 	args interface{}
 }
 
-func testRequest(tbs *TestSession) func(session *Session, method, endpoint string, args interface{}) ([]byte, error) {
-	return func(session *Session, method, endpoint string, args interface{}) ([]byte, error) {
+func testRequest(tbs *TestSession) func(ctx context.Context, session *Session, method, endpoint string, args interface{}) ([]byte, error) {
+	return func(ctx context.Context, session *Session, method, endpoint string, args interface{}) ([]byte, error) {
 		if tbs != nil {
 			tbs.method = method
 			tbs.endpoint = endpoint
@@ -104,8 +111,8 @@ func testRequest(tbs *TestSession) func(session *Session, method, endpoint strin
 	}
 }
 
-func testRequestFailures(tbs *TestSession) func(session *Session, method, endpoint string, args interface{}) ([]byte, error) {
-	return func(session *Session, method, endpoint string, args interface{}) ([]byte, error) {
+func testRequestFailures(tbs *TestSession) func(ctx context.Context, session *Session, method, endpoint string, args interface{}) ([]byte, error) {
+	return func(ctx context.Context, session *Session, method, endpoint string, args interface{}) ([]byte, error) {
 		if tbs != nil {
 			tbs.method = method
 			tbs.endpoint = endpoint
@@ -335,3 +342,81 @@ func TestNewCard(t *testing.T) {
 		t.Error(`Expected endpoint "/cards".`)
 	}
 }
+
+func TestRequestContextCanceled(t *testing.T) {
+	t.Log("TestRequestContextCanceled")
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	session := &Session{
+		apiUri: server.URL,
+		requester: doRequest,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := session.GetCardsContext(ctx)
+	if err == nil {
+		t.Fatal("Expected GetCardsContext to fail when the context expires")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got: %s", err)
+	}
+}
+
+func TestGetSessionContextCanceled(t *testing.T) {
+	t.Log("TestGetSessionContextCanceled")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := getSession(ctx, "http://127.0.0.1:0", "access", "secret", nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", err)
+	}
+}
+
+func TestSessionApplication(t *testing.T) {
+	t.Log("TestSessionApplication")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/sessions" {
+			w.Header().Set("Authorization", "token")
+			w.Write([]byte(`{"apiApplicationId": 77, "name": "payroll", "accessKey": "access"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "Card not found", "error": "NOT_FOUND"}`))
+	}))
+	defer server.Close()
+
+	var logged bytes.Buffer
+	session, err := GetTestSession("access", "secret",
+		WithBaseURL(server.URL),
+		WithRetryPolicy(NoRetries),
+		WithLogger(slog.New(slog.NewTextHandler(&logged, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := session.Application()
+	if app.ApiApplicationId != 77 || app.Name != "payroll" || app.AccessKey != "access" {
+		t.Errorf("Unexpected application: %+v", app)
+	}
+
+	_, err = session.GetCard(1)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an *APIError, got: %v", err)
+	}
+	if apiErr.ApplicationId != 77 || apiErr.Application != "payroll" {
+		t.Errorf("Expected the error to name the application, got %+v", apiErr)
+	}
+	if !strings.Contains(err.Error(), "[application: payroll (77)]") {
+		t.Errorf("Expected the message to name the application, got: %s", err)
+	}
+	if !strings.Contains(logged.String(), "application_id=77 application=payroll") {
+		t.Errorf("Expected the log to name the application:\n%s", logged.String())
+	}
+}
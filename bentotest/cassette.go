@@ -0,0 +1,326 @@
+package bentotest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	bento "github.com/knusbaum/bento-go"
+)
+
+// Redacted replaces secrets in recorded cassettes.
+const Redacted = bento.Redacted
+
+// Cassette is a recording of HTTP interactions with the API, as stored in a
+// fixture file by a Recorder and served back by a Replayer.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded request and its response.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the part of a request that is recorded. URI is the
+// request's path and query; the host is not recorded, so a cassette
+// recorded against the sandbox can be replayed with any base URL that has
+// the same path.
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URI    string      `json:"uri"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+	// BodyEncoding is "base64" if Body is base64 encoded, as it is for
+	// bodies that aren't text, such as receipt uploads.
+	BodyEncoding string `json:"bodyEncoding,omitempty"`
+}
+
+// RecordedResponse is the part of a response that is recorded.
+type RecordedResponse struct {
+	Status       int         `json:"status"`
+	Header       http.Header `json:"header,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"bodyEncoding,omitempty"`
+}
+
+// encodeBody returns body as it is stored in a cassette, with its encoding.
+// Text is stored as is, and anything else base64 encoded, since JSON can
+// only hold text.
+func encodeBody(body string) (string, string) {
+	if utf8.ValidString(body) {
+		return body, ""
+	}
+	return base64.StdEncoding.EncodeToString([]byte(body)), "base64"
+}
+
+// decodeBody reverses encodeBody.
+func decodeBody(body, encoding string) (string, error) {
+	switch encoding {
+	case "":
+		return body, nil
+	case "base64":
+		bs, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return "", fmt.Errorf("bentotest: unable to decode recorded body: %w", err)
+		}
+		return string(bs), nil
+	}
+	return "", fmt.Errorf("bentotest: unknown body encoding %q", encoding)
+}
+
+// LoadCassette reads a cassette from a fixture file.
+func LoadCassette(path string) (*Cassette, error) {
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cassette Cassette
+	err = json.Unmarshal(bs, &cassette)
+	if err != nil {
+		return nil, fmt.Errorf("bentotest: unable to parse cassette %s: %w", path, err)
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette to a fixture file.
+func (c *Cassette) Save(path string) error {
+	bs, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(bs, '\n'), 0644)
+}
+
+// Recorder is an http.RoundTripper that passes requests on to a real
+// transport and records them, with secrets scrubbed, to a cassette file.
+// Use it with bento.WithTransport:
+//
+//	recorder := bentotest.NewRecorder("testdata/cards.json", nil)
+//	session, err := bento.GetTestSession(accessKey, secretKey, bento.WithTransport(recorder))
+//
+// The file is rewritten after every request, so it is complete even if the
+// test fails part way. Authorization headers, secret keys, CVVs and all but
+// the last four digits of card numbers are never written to it.
+type Recorder struct {
+	path string
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder records to the file at path, replacing it if it exists.
+// Requests are sent with next, or http.DefaultTransport if next is nil.
+func NewRecorder(path string, next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{path: path, next: next}
+}
+
+// RoundTrip sends req and records the interaction.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	recordedBody := normalizeMultipart(req.Header.Get("Content-Type"), reqBody)
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	recordedReq := RecordedRequest{
+		Method: req.Method,
+		URI:    req.URL.RequestURI(),
+		Header: scrubHeader(req.Header),
+	}
+	recordedReq.Body, recordedReq.BodyEncoding = encodeBody(bento.Redact(recordedBody))
+	recordedResp := RecordedResponse{
+		Status: resp.StatusCode,
+		Header: scrubHeader(resp.Header),
+	}
+	recordedResp.Body, recordedResp.BodyEncoding = encodeBody(bento.Redact(respBody))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request:  recordedReq,
+		Response: recordedResp,
+	})
+	err = r.cassette.Save(r.path)
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("bentotest: unable to save cassette: %w", err)
+	}
+	return resp, nil
+}
+
+// multipartBoundary replaces the boundary of multipart request bodies in
+// recordings.
+const multipartBoundary = "bentotest-boundary"
+
+// normalizeMultipart replaces the random boundary of a multipart body, such
+// as a receipt upload, with a fixed one, so that the same upload always
+// records the same body and can be matched on replay.
+func normalizeMultipart(contentType string, body []byte) []byte {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return body
+	}
+	return bytes.ReplaceAll(body, []byte(params["boundary"]), []byte(multipartBoundary))
+}
+
+// readBody reads and replaces *body so that it can still be read by
+// someone else.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	bs, err := ioutil.ReadAll(*body)
+	(*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = ioutil.NopCloser(bytes.NewReader(bs))
+	return bs, nil
+}
+
+// Replayer is an http.RoundTripper that answers requests from a cassette
+// without touching the network. Each recorded interaction is used at most
+// once, in the order they were recorded. A request with no matching
+// interaction fails.
+//
+// A request matches an interaction if its method, path and query are the
+// same and its body is the same once scrubbed. The boundaries of multipart
+// bodies are ignored. Headers are not compared.
+type Replayer struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewReplayer loads the cassette at path.
+func NewReplayer(path string) (*Replayer, error) {
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewCassetteReplayer(cassette), nil
+}
+
+// NewCassetteReplayer replays cassette.
+func NewCassetteReplayer(cassette *Cassette) *Replayer {
+	return &Replayer{
+		cassette: cassette,
+		used:     make([]bool, len(cassette.Interactions)),
+	}
+}
+
+// RoundTrip answers req with the first unused interaction that matches it.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	bs, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+	body := bento.Redact(normalizeMultipart(req.Header.Get("Content-Type"), bs))
+	uri := req.URL.RequestURI()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.cassette.Interactions {
+		recorded := interaction.Request
+		if r.used[i] || recorded.Method != req.Method || recorded.URI != uri {
+			continue
+		}
+		recordedBody, err := decodeBody(recorded.Body, recorded.BodyEncoding)
+		if err != nil {
+			return nil, err
+		}
+		if recordedBody != body {
+			continue
+		}
+
+		resp := interaction.Response
+		respBody, err := decodeBody(resp.Body, resp.BodyEncoding)
+		if err != nil {
+			return nil, err
+		}
+		r.used[i] = true
+		header := http.Header{}
+		for key, values := range resp.Header {
+			header[key] = append([]string(nil), values...)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", resp.Status, http.StatusText(resp.Status)),
+			StatusCode:    resp.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(strings.NewReader(respBody)),
+			ContentLength: int64(len(respBody)),
+			Request:       req,
+		}, nil
+	}
+	return nil, fmt.Errorf("bentotest: no recorded interaction matches %s %s", req.Method, uri)
+}
+
+// Unused returns the recorded interactions that have not been replayed yet,
+// so tests can check that everything they expected to happen did.
+func (r *Replayer) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unused []Interaction
+	for i, interaction := range r.cassette.Interactions {
+		if !r.used[i] {
+			unused = append(unused, interaction)
+		}
+	}
+	return unused
+}
+
+// RecordOrReplay replays the cassette at path if it exists, and otherwise
+// records one there by sending requests with next. Delete the file to
+// record it again.
+func RecordOrReplay(path string, next http.RoundTripper) (http.RoundTripper, error) {
+	_, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return NewRecorder(path, next), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewReplayer(path)
+}
+
+// sensitiveHeaders are replaced with Redacted in recordings.
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+func scrubHeader(header http.Header) http.Header {
+	scrubbed := http.Header{}
+	for key, values := range header {
+		scrubbed[key] = append([]string(nil), values...)
+	}
+	for _, key := range sensitiveHeaders {
+		if _, ok := scrubbed[key]; ok {
+			scrubbed[key] = []string{Redacted}
+		}
+	}
+	return scrubbed
+}
@@ -0,0 +1,171 @@
+package bentotest_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bento "github.com/knusbaum/bento-go"
+	"github.com/knusbaum/bento-go/bentotest"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	t.Log("TestRecordAndReplay")
+	dir, err := ioutil.TempDir("", "bentotest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cassette.json")
+
+	// Record against the fake server.
+	server := bentotest.NewServer()
+	card := server.AddCard(bento.Card{Type: bento.EMPLOYEE_CARD, Alias: "Travel"})
+	secrets, _ := server.PanAndCvv(card.CardId)
+
+	transport, err := bentotest.RecordOrReplay(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := transport.(*bentotest.Recorder); !ok {
+		t.Fatalf("Expected to record when the cassette doesn't exist, got %T", transport)
+	}
+	session, err := server.Session(bento.WithTransport(transport))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded, err := session.GetCard(card.CardId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recorded.GetPanAndCvv(); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{bentotest.SecretKey, secrets.Pan, `"` + secrets.Cvv + `"`, "token-"} {
+		if strings.Contains(string(bs), secret) {
+			t.Errorf("Expected %q to be scrubbed from the cassette:\n%s", secret, bs)
+		}
+	}
+	if !strings.Contains(string(bs), "************"+card.LastFour) {
+		t.Errorf("Expected the PAN's last four digits to be kept:\n%s", bs)
+	}
+
+	// Replay with the server gone.
+	transport, err = bentotest.RecordOrReplay(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer, ok := transport.(*bentotest.Replayer)
+	if !ok {
+		t.Fatalf("Expected to replay an existing cassette, got %T", transport)
+	}
+	session, err = bento.GetTestSession(bentotest.AccessKey, bentotest.SecretKey,
+		bento.WithBaseURL(server.URL),
+		bento.WithTransport(replayer),
+		bento.WithRetryPolicy(bento.NoRetries))
+	if err != nil {
+		t.Fatalf("Expected login to replay: %s", err)
+	}
+	replayed, err := session.GetCard(card.CardId)
+	if err != nil {
+		t.Fatalf("Expected GetCard to replay: %s", err)
+	}
+	if replayed.Alias != "Travel" || replayed.LastFour != card.LastFour {
+		t.Errorf("Unexpected replayed card: %+v", replayed)
+	}
+	pan, err := replayed.GetPanAndCvv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pan.Cvv != bentotest.Redacted {
+		t.Errorf("Expected a redacted CVV, got %q", pan.Cvv)
+	}
+	if unused := replayer.Unused(); len(unused) != 0 {
+		t.Errorf("Expected every interaction to be replayed, %d were not", len(unused))
+	}
+
+	// Anything that wasn't recorded fails.
+	if _, err := session.GetCards(); err == nil || !strings.Contains(err.Error(), "no recorded interaction") {
+		t.Errorf("Expected an unmatched request to fail, got: %v", err)
+	}
+	if _, err := session.GetCard(card.CardId); err == nil {
+		t.Error("Expected a replayed interaction to be used only once")
+	}
+}
+
+func TestRecordAndReplayReceipt(t *testing.T) {
+	t.Log("TestRecordAndReplayReceipt")
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	// A PNG header followed by bytes that aren't valid UTF-8.
+	png := []byte("\x89PNG\r\n\x1a\n\xff\xfe\x00receipt")
+
+	server := bentotest.NewServer()
+	seeded := server.AddTransaction(bento.Transaction{Amount: 42})
+	session, err := server.Session(bento.WithTransport(bentotest.NewRecorder(path, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err := session.GetTransaction(seeded.CardTransactionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt, err := tx.UploadReceipt("lunch.png", bytes.NewReader(png))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.DownloadReceipt(receipt.ReceiptId, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+
+	cassette, err := bentotest.LoadCassette(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload := cassette.Interactions[2].Request
+	if upload.Method != "POST" || upload.BodyEncoding != "base64" {
+		t.Errorf("Expected the upload to be recorded base64 encoded, got %+v", upload)
+	}
+
+	replayer, err := bentotest.NewReplayer(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err = bento.GetTestSession(bentotest.AccessKey, bentotest.SecretKey,
+		bento.WithBaseURL(server.URL),
+		bento.WithTransport(replayer),
+		bento.WithRetryPolicy(bento.NoRetries))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx, err = session.GetTransaction(seeded.CardTransactionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed, err := tx.UploadReceipt("lunch.png", bytes.NewReader(png))
+	if err != nil {
+		t.Fatalf("Expected the upload to replay: %s", err)
+	}
+	if *replayed != *receipt {
+		t.Errorf("Expected the recorded receipt %+v, got %+v", receipt, replayed)
+	}
+	var buf bytes.Buffer
+	if err := tx.DownloadReceipt(receipt.ReceiptId, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), png) {
+		t.Errorf("Expected the receipt's bytes to replay intact, got %q", buf.Bytes())
+	}
+	if unused := replayer.Unused(); len(unused) != 0 {
+		t.Errorf("Expected every interaction to be replayed, %d were not", len(unused))
+	}
+}
@@ -0,0 +1,267 @@
+package bentotest
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Fault describes how to misbehave when answering a request. The zero Fault
+// passes the request through untouched.
+type Fault struct {
+	// Delay is how long to wait before answering. The wait ends early if
+	// the client gives up on the request.
+	Delay time.Duration
+	// Status, if non-zero, replaces the real response with one with this
+	// status, Header and Body.
+	Status int
+	Header http.Header
+	Body   string
+	// Truncate serves the real response with only the first half of its
+	// body.
+	Truncate bool
+}
+
+// Pass is a Fault that lets a request through, for use in sequences.
+func Pass() Fault {
+	return Fault{}
+}
+
+// HTML500 answers with an HTML error page, as a misconfigured proxy would.
+func HTML500() Fault {
+	return Fault{
+		Status: http.StatusInternalServerError,
+		Header: http.Header{"Content-Type": {"text/html"}},
+		Body:   "<html>500 Error</html>",
+	}
+}
+
+// Unavailable answers with 503 Service Unavailable and an API error body.
+func Unavailable() Fault {
+	return Fault{
+		Status: http.StatusServiceUnavailable,
+		Header: http.Header{"Content-Type": {"application/json"}},
+		Body:   `{"message": "Service unavailable", "error": "SERVICE_UNAVAILABLE"}`,
+	}
+}
+
+// Slow answers normally after waiting for delay.
+func Slow(delay time.Duration) Fault {
+	return Fault{Delay: delay}
+}
+
+// TruncatedJSON answers with the first half of the real response body.
+func TruncatedJSON() Fault {
+	return Fault{Truncate: true}
+}
+
+// RateLimited answers with 429 Too Many Requests and a Retry-After header
+// asking the client to wait retryAfter, rounded up to whole seconds.
+func RateLimited(retryAfter time.Duration) Fault {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	return Fault{
+		Status: http.StatusTooManyRequests,
+		Header: http.Header{
+			"Content-Type": {"application/json"},
+			"Retry-After":  {strconv.Itoa(seconds)},
+		},
+		Body: `{"message": "Too many requests", "error": "RATE_LIMITED"}`,
+	}
+}
+
+// ExpiredToken answers with 401 Unauthorized as if the request's token had
+// expired. Unlike Server.ExpireTokens, the token keeps working for later
+// requests.
+func ExpiredToken() Fault {
+	return Fault{
+		Status: http.StatusUnauthorized,
+		Header: http.Header{"Content-Type": {"application/json"}},
+		Body:   `{"message": "Invalid or expired authorization token", "error": "UNAUTHORIZED"}`,
+	}
+}
+
+// NonJSON answers 200 OK with body, which should not be valid JSON.
+func NonJSON(body string) Fault {
+	return Fault{
+		Status: http.StatusOK,
+		Header: http.Header{"Content-Type": {"text/plain"}},
+		Body:   body,
+	}
+}
+
+// rule applies faults to requests matching method and pattern. A sequenced
+// rule uses up its faults one request at a time; a random rule applies its
+// single fault with the given probability forever.
+type rule struct {
+	method  string
+	pattern string
+	faults  []Fault
+	// random rules apply their single fault with the given probability,
+	// rather than using up their faults in sequence.
+	random      bool
+	probability float64
+}
+
+func (r *rule) matches(req *http.Request) bool {
+	if r.method != "" && r.method != req.Method {
+		return false
+	}
+	ok, _ := path.Match(r.pattern, req.URL.Path)
+	return ok
+}
+
+// FaultInjector is an http.Handler that misbehaves according to the faults
+// injected into it, and otherwise passes requests to the handler it wraps.
+// It is safe for concurrent use.
+type FaultInjector struct {
+	next http.Handler
+
+	mu    sync.Mutex
+	rules []*rule
+	rand  *rand.Rand
+	hits  int
+}
+
+// NewFaultInjector wraps next.
+func NewFaultInjector(next http.Handler) *FaultInjector {
+	return &FaultInjector{
+		next: next,
+		rand: rand.New(rand.NewSource(1)),
+	}
+}
+
+// Inject makes the next len(faults) requests matching method and pattern
+// misbehave, in order. After that, matching requests pass through again.
+// An empty method matches any method, and pattern is matched against the
+// request path with path.Match, so "/cards/*" matches every card.
+//
+// Rules are checked in the order they were injected and the first one that
+// matches applies.
+func (f *FaultInjector) Inject(method, pattern string, faults ...Fault) {
+	if len(faults) == 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, &rule{
+		method:  method,
+		pattern: pattern,
+		faults:  append([]Fault(nil), faults...),
+	})
+}
+
+// InjectRandom makes requests matching method and pattern misbehave with
+// the given probability, until Reset is called.
+func (f *FaultInjector) InjectRandom(method, pattern string, probability float64, fault Fault) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = append(f.rules, &rule{
+		method:      method,
+		pattern:     pattern,
+		faults:      []Fault{fault},
+		random:      true,
+		probability: probability,
+	})
+}
+
+// Seed seeds the random source used by InjectRandom, which is seeded with 1
+// by default so that runs are repeatable.
+func (f *FaultInjector) Seed(seed int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rand = rand.New(rand.NewSource(seed))
+}
+
+// Reset removes every injected fault.
+func (f *FaultInjector) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = nil
+}
+
+// Hits returns how many requests have had a fault applied to them, not
+// counting Pass.
+func (f *FaultInjector) Hits() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.hits
+}
+
+// fault returns the fault to apply to req, if any.
+func (f *FaultInjector) fault(req *http.Request) (Fault, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, r := range f.rules {
+		if !r.matches(req) {
+			continue
+		}
+		if r.random {
+			if f.rand.Float64() >= r.probability {
+				return Fault{}, false
+			}
+			f.hits++
+			return r.faults[0], true
+		}
+
+		fault := r.faults[0]
+		r.faults = r.faults[1:]
+		if len(r.faults) == 0 {
+			f.rules = append(f.rules[:i:i], f.rules[i+1:]...)
+		}
+		if fault.isPass() {
+			return Fault{}, false
+		}
+		f.hits++
+		return fault, true
+	}
+	return Fault{}, false
+}
+
+func (fault Fault) isPass() bool {
+	return fault.Delay == 0 && fault.Status == 0 && !fault.Truncate
+}
+
+// ServeHTTP applies the first matching fault, if any, and otherwise passes
+// the request on.
+func (f *FaultInjector) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	fault, ok := f.fault(req)
+	if !ok {
+		f.next.ServeHTTP(w, req)
+		return
+	}
+
+	if fault.Delay > 0 {
+		timer := time.NewTimer(fault.Delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			return
+		}
+	}
+
+	switch {
+	case fault.Status != 0:
+		for key, values := range fault.Header {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(fault.Status)
+		fmt.Fprint(w, fault.Body)
+	case fault.Truncate:
+		recorder := httptest.NewRecorder()
+		f.next.ServeHTTP(recorder, req)
+		for key, values := range recorder.Header() {
+			w.Header()[key] = values
+		}
+		w.WriteHeader(recorder.Code)
+		body := recorder.Body.Bytes()
+		w.Write(body[:len(body)/2])
+	default:
+		f.next.ServeHTTP(w, req)
+	}
+}
@@ -0,0 +1,223 @@
+package bentotest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	bento "github.com/knusbaum/bento-go"
+	"github.com/knusbaum/bento-go/bentotest"
+)
+
+func TestFaultHTML500(t *testing.T) {
+	t.Log("TestFaultHTML500")
+	server, session := newSession(t)
+	defer server.Close()
+	card := server.AddCard(bento.Card{Type: bento.EMPLOYEE_CARD})
+
+	server.Faults.Inject("GET", "/cards/*", bentotest.HTML500())
+	_, err := session.GetCard(card.CardId)
+	var apiErr *bento.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 500 || string(apiErr.Body) != "<html>500 Error</html>" {
+		t.Fatalf("Expected an HTML 500 APIError, got: %v", err)
+	}
+
+	// The sequence is used up, so the next request succeeds.
+	if _, err := session.GetCard(card.CardId); err != nil {
+		t.Errorf("Expected the fault to apply once, got: %v", err)
+	}
+	if server.Faults.Hits() != 1 {
+		t.Errorf("Expected 1 hit, got %d", server.Faults.Hits())
+	}
+}
+
+func TestFaultSequenceRetried(t *testing.T) {
+	t.Log("TestFaultSequenceRetried")
+	server := bentotest.NewServer()
+	defer server.Close()
+	session, err := server.Session(bento.WithRetryPolicy(bento.RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: time.Millisecond,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.Faults.Inject("GET", "/cards", bentotest.Unavailable(), bentotest.HTML500(), bentotest.Pass(), bentotest.HTML500())
+	if _, err := session.GetCards(); err != nil {
+		t.Errorf("Expected GetCards to succeed on the third attempt, got: %v", err)
+	}
+	if server.Faults.Hits() != 2 {
+		t.Errorf("Expected 2 hits, got %d", server.Faults.Hits())
+	}
+}
+
+func TestFaultRateLimited(t *testing.T) {
+	t.Log("TestFaultRateLimited")
+	server := bentotest.NewServer()
+	defer server.Close()
+	session, err := server.Session(bento.WithRetryPolicy(bento.RetryPolicy{
+		MaxAttempts:    2,
+		InitialBackoff: time.Hour,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.Faults.Inject("", "/businesses/me", bentotest.RateLimited(time.Second))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	start := time.Now()
+	if _, err := session.GetBusinessContext(ctx); err != nil {
+		t.Fatalf("Expected the retry to honor Retry-After and succeed, got: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Expected to wait for Retry-After, waited %s", elapsed)
+	}
+
+	server.Faults.Inject("", "/businesses/me", bentotest.RateLimited(time.Second))
+	session, _ = server.Session()
+	if _, err := session.GetBusiness(); !bento.IsRateLimited(err) {
+		t.Errorf("Expected IsRateLimited without retries, got: %v", err)
+	}
+}
+
+func TestFaultSlow(t *testing.T) {
+	t.Log("TestFaultSlow")
+	server, session := newSession(t)
+	defer server.Close()
+
+	server.Faults.Inject("GET", "/cards", bentotest.Slow(time.Minute))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := session.GetCardsContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+}
+
+func TestFaultTruncatedAndNonJSON(t *testing.T) {
+	t.Log("TestFaultTruncatedAndNonJSON")
+	server, session := newSession(t)
+	defer server.Close()
+	server.AddCard(bento.Card{Type: bento.EMPLOYEE_CARD})
+
+	server.Faults.Inject("GET", "/cards", bentotest.TruncatedJSON(), bentotest.NonJSON("OK"))
+	for i := 0; i < 2; i++ {
+		_, err := session.GetCards()
+		if err == nil || !strings.Contains(err.Error(), "non-json") {
+			t.Errorf("Expected a non-json error, got: %v", err)
+		}
+	}
+}
+
+func TestFaultTruncatedSecretsRedacted(t *testing.T) {
+	t.Log("TestFaultTruncatedSecretsRedacted")
+	server, session := newSession(t)
+	defer server.Close()
+	seeded := server.AddCard(bento.Card{Type: bento.EMPLOYEE_CARD})
+	secrets, _ := server.PanAndCvv(seeded.CardId)
+	card, err := session.GetCard(seeded.CardId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	session.SetStructuredLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	server.Faults.Inject("GET", "/cards/*/pan", bentotest.TruncatedJSON())
+	_, err = card.GetPanAndCvv()
+	if err == nil {
+		t.Fatal("Expected a truncated response to fail")
+	}
+	for _, leak := range []string{secrets.Pan[:11], `"` + secrets.Cvv} {
+		if strings.Contains(err.Error(), leak) || strings.Contains(logs.String(), leak) {
+			t.Errorf("Expected %q to be redacted:\n%s\n%s", leak, err, logs.String())
+		}
+	}
+}
+
+func TestFaultExpiredToken(t *testing.T) {
+	t.Log("TestFaultExpiredToken")
+	server := bentotest.NewServer()
+	defer server.Close()
+	reauths := 0
+	session, err := server.Session(bento.WithReauthHook(func(bento.ReauthEvent) { reauths++ }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server.Faults.Inject("GET", "/cards", bentotest.ExpiredToken())
+	if _, err := session.GetCards(); err != nil {
+		t.Fatalf("Expected the session to log in again and succeed, got: %v", err)
+	}
+	if reauths != 1 {
+		t.Errorf("Expected 1 re-login, got %d", reauths)
+	}
+}
+
+func TestFaultRandom(t *testing.T) {
+	t.Log("TestFaultRandom")
+	server, session := newSession(t)
+	defer server.Close()
+
+	server.Faults.InjectRandom("GET", "/cards", 0.5, bentotest.HTML500())
+	failures := 0
+	for i := 0; i < 100; i++ {
+		if _, err := session.GetCards(); err != nil {
+			failures++
+		}
+	}
+	if failures < 25 || failures > 75 || failures != server.Faults.Hits() {
+		t.Errorf("Expected about half of 100 requests to fail, %d did (%d hits)", failures, server.Faults.Hits())
+	}
+
+	server.Faults.Reset()
+	if _, err := session.GetCards(); err != nil {
+		t.Errorf("Expected no faults after Reset, got: %v", err)
+	}
+}
+
+func TestFaultRandomNever(t *testing.T) {
+	t.Log("TestFaultRandomNever")
+	server, session := newSession(t)
+	defer server.Close()
+
+	server.Faults.InjectRandom("GET", "/cards", 0, bentotest.HTML500())
+	for i := 0; i < 10; i++ {
+		if _, err := session.GetCards(); err != nil {
+			t.Fatalf("Expected a 0%% fault never to fire, got: %v", err)
+		}
+	}
+	if hits := server.Faults.Hits(); hits != 0 {
+		t.Errorf("Expected no hits, got %d", hits)
+	}
+}
+
+func TestFaultInjectorWrapsHandler(t *testing.T) {
+	t.Log("TestFaultInjectorWrapsHandler")
+	injector := bentotest.NewFaultInjector(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	injector.Inject("POST", "/things/*", bentotest.HTML500())
+
+	for _, c := range []struct {
+		method, path string
+		status       int
+	}{
+		{"GET", "/things/1", 200},
+		{"POST", "/other", 200},
+		{"POST", "/things/1", 500},
+		{"POST", "/things/1", 200},
+	} {
+		recorder := httptest.NewRecorder()
+		injector.ServeHTTP(recorder, httptest.NewRequest(c.method, c.path, nil))
+		if recorder.Code != c.status {
+			t.Errorf("%s %s: expected %d, got %d", c.method, c.path, c.status, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,1156 @@
+/*
+Package bentotest provides an in-memory fake of the Bento API, for testing
+code that uses a *bento.Session without talking to the sandbox.
+
+	server := bentotest.NewServer()
+	defer server.Close()
+
+	card := server.AddCard(bento.Card{Type: bento.EMPLOYEE_CARD, Alias: "Travel"})
+	session, err := server.Session()
+	...
+	card, err := session.GetCard(card.CardId)
+
+The fake keeps its state in memory: cards created, updated and deleted through
+a session can be inspected with Server.Card and Server.Cards, and test data can
+be seeded with AddCard, AddUser and AddTransaction.
+
+To test how code copes with Bento misbehaving, inject faults into the server:
+
+	// Fail the next two card lookups, then behave again.
+	server.Faults.Inject("GET", "/cards/*", bentotest.HTML500(), bentotest.RateLimited(time.Second))
+	// Slow down a tenth of all requests.
+	server.Faults.InjectRandom("", "*", 0.1, bentotest.Slow(time.Second))
+
+FaultInjector can also wrap any other http.Handler.
+
+To run tests against interactions captured from the real sandbox, record
+them once with a Recorder and replay them with a Replayer. Both are
+http.RoundTrippers for bento.WithTransport; RecordOrReplay picks one based
+on whether the fixture file exists.
+*/
+package bentotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	bento "github.com/knusbaum/bento-go"
+)
+
+// Default credentials accepted by a new Server.
+const (
+	AccessKey = "test-access-key"
+	SecretKey = "test-secret-key"
+)
+
+// Server is a fake Bento API. It implements http.Handler, and NewServer
+// serves it with an httptest.Server.
+type Server struct {
+	// URL is the base URL of the running server, suitable for
+	// bento.WithBaseURL.
+	URL string
+
+	// AccessKey and SecretKey are the credentials accepted by POST
+	// /sessions. They default to the package constants of the same name.
+	AccessKey string
+	SecretKey string
+
+	// Faults sits in front of the fake API, and can be used to make it
+	// misbehave.
+	Faults *FaultInjector
+
+	httpServer *httptest.Server
+
+	mu           sync.Mutex
+	clock        int64
+	nextId       int64
+	tokens       map[string]bool
+	application  bento.ApiApplication
+	business     bento.Business
+	cards        map[int64]*cardState
+	cardOrder    []int64
+	users        map[int64]*bento.User
+	userOrder    []int64
+	addresses    map[int64][]bento.Address // by user ID
+	categories   []bento.Category
+	transactions []bento.Transaction
+	receipts     map[int64][]*receiptState
+	maxPageSize  int
+}
+
+type cardState struct {
+	card    bento.Card
+	pan     string
+	cvv     string
+	billing *bento.Address
+}
+
+type receiptState struct {
+	receipt bento.Receipt
+	data    []byte
+}
+
+// NewServer starts a fake Bento API with a single business and no cards or
+// transactions. The caller must call Close when done.
+func NewServer() *Server {
+	s := NewHandler()
+	s.httpServer = httptest.NewServer(s)
+	s.URL = s.httpServer.URL
+	return s
+}
+
+// NewHandler returns a fake Bento API that is not being served, for callers
+// that want to mount it on their own server or wrap it in middleware.
+func NewHandler() *Server {
+	s := &Server{
+		AccessKey: AccessKey,
+		SecretKey: SecretKey,
+		nextId:    1000,
+		tokens:    make(map[string]bool),
+		cards:     make(map[int64]*cardState),
+		receipts:  make(map[int64][]*receiptState),
+		users:     make(map[int64]*bento.User),
+		addresses: make(map[int64][]bento.Address),
+		categories: []bento.Category{
+			{TransactionCategoryId: 1, Name: "Airlines", Group: "Travel", Mccs: []int64{3000, 3001, 4511}},
+			{TransactionCategoryId: 2, Name: "Hotels", Group: "Travel", Mccs: []int64{3501, 7011}},
+			{TransactionCategoryId: 3, Name: "Restaurants", Group: "Meals", Mccs: []int64{5812, 5813, 5814}},
+			{TransactionCategoryId: 4, Name: "Office Supplies", Group: "Office", Mccs: []int64{5111, 5943}},
+			{TransactionCategoryId: 5, Name: "Gas Stations", Group: "Auto", Mccs: []int64{5541, 5542}},
+			{TransactionCategoryId: 6, Name: "Software", Group: "Office", Mccs: []int64{5734, 5817}},
+		},
+		business: bento.Business{
+			BusinessId:        1,
+			CompanyName:       "Test Company Inc",
+			NameOnCard:        "Test Company",
+			Phone:             "5555550100",
+			AccountNumber:     "000000001",
+			BusinessStructure: "LLC",
+			Status:            "APPROVED",
+			ApprovalStatus:    "Approved",
+			Balance:           10000,
+			TimeZone:          "America/Los_Angeles",
+			Addresses: []bento.Address{{
+				Active:      true,
+				AddressType: bento.BUSINESS_ADDRESS,
+				City:        "San Francisco",
+				Id:          1,
+				State:       "CA",
+				Street:      "1 Market Street",
+				ZipCode:     "94105",
+			}},
+		},
+	}
+	s.Faults = NewFaultInjector(http.HandlerFunc(s.serveAPI))
+	s.application = bento.ApiApplication{
+		ApiApplicationId: 1,
+		Name:             "bentotest",
+		AccessKey:        s.AccessKey,
+		Business:         s.business,
+	}
+	return s
+}
+
+// Close shuts down the server started by NewServer.
+func (s *Server) Close() {
+	if s.httpServer != nil {
+		s.httpServer.Close()
+	}
+}
+
+// Session logs in to the server and returns a session pointed at it. opts
+// are applied after the option setting the base URL. Retries are disabled
+// unless opts enable them, so that tests see failures immediately.
+func (s *Server) Session(opts ...bento.Option) (*bento.Session, error) {
+	opts = append([]bento.Option{
+		bento.WithBaseURL(s.URL),
+		bento.WithRetryPolicy(bento.NoRetries),
+	}, opts...)
+	return bento.GetTestSession(s.AccessKey, s.SecretKey, opts...)
+}
+
+// ExpireTokens invalidates every token issued so far, as if they had timed
+// out.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = make(map[string]bool)
+}
+
+// SetBusiness replaces the business returned by GET /businesses/me.
+func (s *Server) SetBusiness(business bento.Business) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.business = business
+	s.application.Business = business
+}
+
+// AddCard stores card and returns it as the API would. CardId, LastFour,
+// Expiration, timestamps and statuses are filled in if they are empty.
+func (s *Server) AddCard(card bento.Card) bento.Card {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addCard(card).card
+}
+
+// Card returns the server's copy of a card.
+func (s *Server) Card(cardId int64) (bento.Card, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.cards[cardId]
+	if !ok {
+		return bento.Card{}, false
+	}
+	return state.card, true
+}
+
+// Cards returns the server's copy of every card, in the order they were
+// created.
+func (s *Server) Cards() []bento.Card {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cards := make([]bento.Card, 0, len(s.cardOrder))
+	for _, id := range s.cardOrder {
+		cards = append(cards, s.cards[id].card)
+	}
+	return cards
+}
+
+// PanAndCvv returns the full card number and CVV the server generated for a
+// card.
+func (s *Server) PanAndCvv(cardId int64) (bento.PanAndCvv, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.cards[cardId]
+	if !ok {
+		return bento.PanAndCvv{}, false
+	}
+	return bento.PanAndCvv{Pan: state.pan, Cvv: state.cvv}, true
+}
+
+// AddTransaction stores tx and returns it as the API would. If tx.Card only
+// carries a CardId, it is replaced by the server's copy of that card.
+func (s *Server) AddTransaction(tx bento.Transaction) bento.Transaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if tx.CardTransactionId == 0 {
+		tx.CardTransactionId = s.newId()
+	}
+	if tx.TransactionDate == 0 {
+		tx.TransactionDate = s.now()
+	}
+	if tx.Currency == "" {
+		tx.Currency = "USD"
+	}
+	if tx.Card != nil {
+		if state, ok := s.cards[tx.Card.CardId]; ok {
+			card := state.card
+			tx.Card = &card
+		}
+	}
+	s.transactions = append(s.transactions, tx)
+	return tx
+}
+
+// SetCategories replaces the catalog of transaction categories, which
+// starts with a handful of common ones.
+func (s *Server) SetCategories(categories []bento.Category) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.categories = append([]bento.Category{}, categories...)
+}
+
+// SetMaxPageSize caps the number of transactions returned in one page, as
+// the API does, whatever size is asked for. Zero removes the cap.
+func (s *Server) SetMaxPageSize(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxPageSize = size
+}
+
+// AddUser stores user and returns it as the API would.
+func (s *Server) AddUser(user bento.User) bento.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.addUser(user)
+}
+
+// User returns the server's copy of a user.
+func (s *Server) User(userId int64) (bento.User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.users[userId]
+	if !ok {
+		return bento.User{}, false
+	}
+	return *user, true
+}
+
+// Users returns the server's copy of every user, including deleted ones, in
+// the order they were created.
+func (s *Server) Users() []bento.User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := make([]bento.User, 0, len(s.userOrder))
+	for _, id := range s.userOrder {
+		users = append(users, *s.users[id])
+	}
+	return users
+}
+
+// Transaction returns the server's copy of a transaction.
+func (s *Server) Transaction(transactionId int64) (bento.Transaction, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, tx := range s.transactions {
+		if tx.CardTransactionId == transactionId {
+			return tx, true
+		}
+	}
+	return bento.Transaction{}, false
+}
+
+// Transactions returns the server's copy of every transaction.
+func (s *Server) Transactions() []bento.Transaction {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]bento.Transaction(nil), s.transactions...)
+}
+
+func (s *Server) newId() int64 {
+	s.nextId++
+	return s.nextId
+}
+
+// now returns the current time in milliseconds. Every call returns a later
+// time than the one before, so UpdatedOn always changes on update.
+func (s *Server) now() int64 {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	if now <= s.clock {
+		now = s.clock + 1
+	}
+	s.clock = now
+	return now
+}
+
+func (s *Server) addUser(user bento.User) *bento.User {
+	if user.UserId == 0 {
+		user.UserId = s.newId()
+	}
+	user.Created = s.now()
+	if user.BentoType == "" {
+		user.BentoType = "com.bentoforbusiness.entity.user.User"
+	}
+	s.users[user.UserId] = &user
+	s.userOrder = append(s.userOrder, user.UserId)
+	return &user
+}
+
+func (s *Server) addCard(card bento.Card) *cardState {
+	if card.CardId == 0 {
+		card.CardId = s.newId()
+	}
+	state := &cardState{
+		pan: fmt.Sprintf("4000%012d", card.CardId),
+		cvv: fmt.Sprintf("%03d", card.CardId%1000),
+	}
+	if len(card.LastFour) == 4 {
+		state.pan = state.pan[:12] + card.LastFour
+	} else {
+		card.LastFour = state.pan[12:]
+	}
+	if card.Expiration == "" {
+		card.Expiration = time.Now().AddDate(3, 0, 0).Format("0106")
+	}
+	if card.LifecycleStatus == "" {
+		if card.VirtualCard {
+			card.LifecycleStatus = "ACTIVATED"
+		} else {
+			card.LifecycleStatus = "CREATED"
+		}
+	}
+	if card.Status == "" {
+		if card.VirtualCard {
+			card.Status = bento.STATUS_TURNED_ON
+		} else {
+			card.Status = bento.STATUS_TURNED_OFF
+		}
+	}
+	if card.CreatedOn == 0 {
+		card.CreatedOn = s.now()
+	}
+	if card.UpdatedOn == 0 {
+		card.UpdatedOn = card.CreatedOn
+	}
+	if card.BentoType == "" {
+		card.BentoType = "com.bentoforbusiness.entity.card.Card"
+	}
+	state.card = card
+	if _, exists := s.cards[card.CardId]; !exists {
+		s.cardOrder = append(s.cardOrder, card.CardId)
+	}
+	s.cards[card.CardId] = state
+	return state
+}
+
+// apiError is the error body the API returns.
+type apiError struct {
+	Message string `json:"message"`
+	Error   string `json:"error"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	bs, err := json.Marshal(v)
+	if err != nil {
+		status = http.StatusInternalServerError
+		bs, _ = json.Marshal(apiError{err.Error(), "INTERNAL_ERROR"})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(bs)
+}
+
+func writeError(w http.ResponseWriter, status int, code, format string, args ...interface{}) {
+	writeJSON(w, status, apiError{fmt.Sprintf(format, args...), code})
+}
+
+func notFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, "NOT_FOUND", "No resource at %s %s", r.Method, r.URL.Path)
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "%s is not supported on %s", r.Method, r.URL.Path)
+}
+
+// decode reads the request body into v.
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	bs, err := ioutil.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(bs, v)
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_JSON", "Unable to parse request body: %s", err)
+		return false
+	}
+	return true
+}
+
+// ServeHTTP implements the Bento API, misbehaving according to the faults
+// injected into s.Faults.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.Faults.ServeHTTP(w, r)
+}
+
+func (s *Server) serveAPI(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if path[0] == "sessions" && len(path) == 1 {
+		s.login(w, r)
+		return
+	}
+
+	if !s.tokens[r.Header.Get("Authorization")] {
+		writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Invalid or expired authorization token")
+		return
+	}
+
+	switch path[0] {
+	case "businesses":
+		if len(path) == 2 && path[1] == "me" && r.Method == "GET" {
+			writeJSON(w, http.StatusOK, s.business)
+			return
+		}
+		if len(path) >= 3 && path[1] == "me" && path[2] == "addresses" {
+			s.serveAddresses(w, r, &s.business.Addresses, path[3:])
+			return
+		}
+	case "cards":
+		s.serveCards(w, r, path[1:])
+		return
+	case "transactions":
+		s.serveTransactions(w, r, path[1:])
+		return
+	case "users":
+		s.serveUsers(w, r, path[1:])
+		return
+	case "categories":
+		if len(path) == 1 && r.Method == "GET" {
+			writeJSON(w, http.StatusOK, s.categories)
+			return
+		}
+	}
+	notFound(w, r)
+}
+
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		methodNotAllowed(w, r)
+		return
+	}
+	var creds struct {
+		AccessKey string `json:"accessKey"`
+		SecretKey string `json:"secretKey"`
+	}
+	if !decode(w, r, &creds) {
+		return
+	}
+	if creds.AccessKey != s.AccessKey || creds.SecretKey != s.SecretKey {
+		writeError(w, http.StatusUnauthorized, "INVALID_CREDENTIALS", "Invalid access key or secret key")
+		return
+	}
+
+	token := fmt.Sprintf("token-%d", s.newId())
+	s.tokens[token] = true
+	w.Header().Set("Authorization", token)
+	writeJSON(w, http.StatusOK, s.application)
+}
+
+func (s *Server) serveCards(w http.ResponseWriter, r *http.Request, path []string) {
+	if len(path) == 0 {
+		switch r.Method {
+		case "GET":
+			cards := make([]bento.Card, 0, len(s.cardOrder))
+			for _, id := range s.cardOrder {
+				cards = append(cards, s.cards[id].card)
+			}
+			writeJSON(w, http.StatusOK, cards)
+		case "POST":
+			s.createCard(w, r)
+		default:
+			methodNotAllowed(w, r)
+		}
+		return
+	}
+
+	cardId, err := strconv.ParseInt(path[0], 10, 64)
+	if err != nil {
+		notFound(w, r)
+		return
+	}
+	state, ok := s.cards[cardId]
+	if !ok {
+		writeError(w, http.StatusNotFound, "CARD_NOT_FOUND", "Card %d not found", cardId)
+		return
+	}
+
+	if len(path) == 1 {
+		switch r.Method {
+		case "GET":
+			writeJSON(w, http.StatusOK, state.card)
+		case "PUT":
+			s.updateCard(w, r, state)
+		case "DELETE":
+			state.card.Status = bento.STATUS_CANCELED
+			state.card.LifecycleStatus = "TERMINATED"
+			state.card.UpdatedOn = s.now()
+			writeJSON(w, http.StatusOK, state.card)
+		default:
+			methodNotAllowed(w, r)
+		}
+		return
+	}
+	if len(path) != 2 {
+		notFound(w, r)
+		return
+	}
+
+	switch path[1] {
+	case "activation":
+		if r.Method != "POST" {
+			methodNotAllowed(w, r)
+			return
+		}
+		s.activateCard(w, r, state)
+	case "reissue":
+		if r.Method != "POST" {
+			methodNotAllowed(w, r)
+			return
+		}
+		s.reissueCard(w, state)
+	case "pan":
+		if r.Method != "GET" {
+			methodNotAllowed(w, r)
+			return
+		}
+		writeJSON(w, http.StatusOK, bento.PanAndCvv{Pan: state.pan, Cvv: state.cvv})
+	case "billingAddress":
+		s.serveBillingAddress(w, r, state)
+	default:
+		notFound(w, r)
+	}
+}
+
+func validCardType(cardType bento.CardType) bool {
+	switch cardType {
+	case bento.BUSINESS_OWNER_CARD, bento.EMPLOYEE_CARD, bento.CATEGORY_CARD:
+		return true
+	}
+	return false
+}
+
+func (s *Server) createCard(w http.ResponseWriter, r *http.Request) {
+	var fields map[string]json.RawMessage
+	if !decode(w, r, &fields) {
+		return
+	}
+	// The server assigns lastFour, so like the real API ignore whatever
+	// the client sent, even if it isn't a string.
+	delete(fields, "lastFour")
+	bs, _ := json.Marshal(fields)
+	var card bento.Card
+	err := json.Unmarshal(bs, &card)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_JSON", "Unable to parse request body: %s", err)
+		return
+	}
+	if !validCardType(card.Type) {
+		writeError(w, http.StatusBadRequest, "INVALID_CARD_TYPE", "Invalid card type %q", card.Type)
+		return
+	}
+	if card.Type == bento.CATEGORY_CARD && card.TransactionCategoryId == 0 {
+		writeError(w, http.StatusBadRequest, "MISSING_CATEGORY", "A CategoryCard requires a transactionCategoryId")
+		return
+	}
+	if card.User.UserId != 0 {
+		user, ok := s.users[card.User.UserId]
+		if !ok || user.Deleted {
+			writeError(w, http.StatusBadRequest, "USER_NOT_FOUND", "User %d not found", card.User.UserId)
+			return
+		}
+		card.User = *user
+	}
+
+	// The server decides these.
+	card.CardId = 0
+	card.LastFour = ""
+	card.Expiration = ""
+	card.LifecycleStatus = ""
+	card.Status = ""
+	card.CreatedOn = 0
+	card.UpdatedOn = 0
+	writeJSON(w, http.StatusOK, s.addCard(card).card)
+}
+
+func (s *Server) updateCard(w http.ResponseWriter, r *http.Request, state *cardState) {
+	if state.card.Status == bento.STATUS_CANCELED {
+		writeError(w, http.StatusBadRequest, "CARD_CANCELED", "Card %d is canceled", state.card.CardId)
+		return
+	}
+
+	// Decoding into a copy of the stored card applies only the fields
+	// present in the request, so partial updates work.
+	card := state.card
+	card.AllowedDays = append([]string(nil), card.AllowedDays...)
+	card.AllowedCategories = append([]bento.Category(nil), card.AllowedCategories...)
+	card.Permissions = nil
+	if !decode(w, r, &card) {
+		return
+	}
+
+	switch card.Status {
+	case bento.STATUS_TURNED_ON, bento.STATUS_TURNED_OFF, state.card.Status:
+	default:
+		writeError(w, http.StatusBadRequest, "INVALID_STATUS", "Status cannot be set to %q", card.Status)
+		return
+	}
+
+	if card.User.UserId != state.card.User.UserId {
+		user, ok := s.users[card.User.UserId]
+		if !ok || user.Deleted {
+			writeError(w, http.StatusBadRequest, "USER_NOT_FOUND", "User %d not found", card.User.UserId)
+			return
+		}
+		card.User = *user
+	}
+
+	// Read-only fields.
+	card.CardId = state.card.CardId
+	card.Type = state.card.Type
+	card.LifecycleStatus = state.card.LifecycleStatus
+	card.LastFour = state.card.LastFour
+	card.Expiration = state.card.Expiration
+	card.VirtualCard = state.card.VirtualCard
+	card.AvailableAmount = state.card.AvailableAmount
+	card.CreatedOn = state.card.CreatedOn
+	card.Permissions = state.card.Permissions
+	card.BentoType = state.card.BentoType
+	card.UpdatedOn = s.now()
+
+	state.card = card
+	writeJSON(w, http.StatusOK, state.card)
+}
+
+func (s *Server) reissueCard(w http.ResponseWriter, state *cardState) {
+	if state.card.Status == bento.STATUS_CANCELED {
+		writeError(w, http.StatusBadRequest, "CARD_CANCELED", "Card %d is canceled", state.card.CardId)
+		return
+	}
+	// A reissued card keeps its id but gets a new number, and a physical
+	// card has to be activated again.
+	id := s.newId()
+	state.pan = fmt.Sprintf("4000%012d", id)
+	state.cvv = fmt.Sprintf("%03d", id%1000)
+	state.card.LastFour = state.pan[12:]
+	state.card.Expiration = time.Now().AddDate(3, 0, 0).Format("0106")
+	if !state.card.VirtualCard {
+		state.card.LifecycleStatus = "CREATED"
+		state.card.Status = bento.STATUS_TURNED_OFF
+	}
+	state.card.UpdatedOn = s.now()
+	writeJSON(w, http.StatusOK, state.card)
+}
+
+func (s *Server) activateCard(w http.ResponseWriter, r *http.Request, state *cardState) {
+	var body struct {
+		LastFour string `json:"lastFour"`
+	}
+	if !decode(w, r, &body) {
+		return
+	}
+	if state.card.LifecycleStatus == "ACTIVATED" {
+		writeError(w, http.StatusBadRequest, "ALREADY_ACTIVATED", "Card %d is already activated", state.card.CardId)
+		return
+	}
+	if body.LastFour != state.card.LastFour {
+		writeError(w, http.StatusBadRequest, "INVALID_LAST_FOUR", "Last four digits do not match card %d", state.card.CardId)
+		return
+	}
+	state.card.LifecycleStatus = "ACTIVATED"
+	state.card.Status = bento.STATUS_TURNED_ON
+	state.card.UpdatedOn = s.now()
+	writeJSON(w, http.StatusOK, state.card)
+}
+
+func (s *Server) serveBillingAddress(w http.ResponseWriter, r *http.Request, state *cardState) {
+	switch r.Method {
+	case "GET":
+		if state.billing == nil {
+			writeError(w, http.StatusNotFound, "ADDRESS_NOT_FOUND", "Card %d has no billing address", state.card.CardId)
+			return
+		}
+		writeJSON(w, http.StatusOK, state.billing)
+	case "POST", "PUT":
+		if r.Method == "PUT" && state.billing == nil {
+			writeError(w, http.StatusNotFound, "ADDRESS_NOT_FOUND", "Card %d has no billing address", state.card.CardId)
+			return
+		}
+		var address bento.Address
+		if !decode(w, r, &address) {
+			return
+		}
+		if state.billing != nil {
+			address.Id = state.billing.Id
+		} else {
+			address.Id = s.newId()
+		}
+		address.Active = true
+		state.billing = &address
+		writeJSON(w, http.StatusOK, state.billing)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+func (s *Server) serveTransactions(w http.ResponseWriter, r *http.Request, path []string) {
+	if len(path) > 0 {
+		s.serveTransaction(w, r, path)
+		return
+	}
+	if r.Method != "GET" {
+		methodNotAllowed(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	offset, size := 0, len(s.transactions)
+	var err error
+	if value := query.Get("offset"); value != "" {
+		offset, err = strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			writeError(w, http.StatusBadRequest, "INVALID_OFFSET", "Invalid offset %q", value)
+			return
+		}
+	}
+	if value := query.Get("size"); value != "" {
+		size, err = strconv.Atoi(value)
+		if err != nil || size < 1 {
+			writeError(w, http.StatusBadRequest, "INVALID_SIZE", "Invalid size %q", value)
+			return
+		}
+	}
+	if s.maxPageSize > 0 && size > s.maxPageSize {
+		size = s.maxPageSize
+	}
+
+	var filter transactionFilter
+	if value := query.Get("dateStart"); value != "" {
+		filter.dateStart, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_DATE", "Invalid dateStart %q", value)
+			return
+		}
+	}
+	if value := query.Get("dateEnd"); value != "" {
+		filter.dateEnd, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_DATE", "Invalid dateEnd %q", value)
+			return
+		}
+	}
+	if filter.cardIds, err = parseIds(query.Get("cardIds")); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_CARD_IDS", "Invalid cardIds %q", query.Get("cardIds"))
+		return
+	}
+	if filter.categoryIds, err = parseIds(query.Get("categoryIds")); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_CATEGORY_IDS", "Invalid categoryIds %q", query.Get("categoryIds"))
+		return
+	}
+
+	page := bento.Transactions{CardTransactions: []bento.Transaction{}}
+	for _, tx := range s.transactions {
+		if !filter.matches(tx) {
+			continue
+		}
+		if page.Size >= offset && page.Size < offset+size {
+			page.CardTransactions = append(page.CardTransactions, tx)
+		}
+		page.Size++
+		page.Amount += tx.Amount
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (s *Server) serveTransaction(w http.ResponseWriter, r *http.Request, path []string) {
+	transactionId, err := strconv.ParseInt(path[0], 10, 64)
+	if err != nil {
+		notFound(w, r)
+		return
+	}
+	var tx *bento.Transaction
+	for i := range s.transactions {
+		if s.transactions[i].CardTransactionId == transactionId {
+			tx = &s.transactions[i]
+		}
+	}
+	if tx == nil {
+		writeError(w, http.StatusNotFound, "TRANSACTION_NOT_FOUND", "Transaction %d not found", transactionId)
+		return
+	}
+
+	switch {
+	case len(path) == 1:
+		s.getOrUpdateTransaction(w, r, tx)
+	case len(path) == 2 && path[1] == "receipts":
+		s.serveReceipts(w, r, tx)
+	case len(path) == 3 && path[1] == "receipts":
+		s.serveReceipt(w, r, tx, path[2])
+	default:
+		notFound(w, r)
+	}
+}
+
+func (s *Server) getOrUpdateTransaction(w http.ResponseWriter, r *http.Request, tx *bento.Transaction) {
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, tx)
+	case "PUT":
+		// Only the note and tags can be changed.
+		var annotations struct {
+			Note *string  `json:"note"`
+			Tags []string `json:"tags"`
+		}
+		if !decode(w, r, &annotations) {
+			return
+		}
+		if annotations.Note != nil {
+			tx.Note = *annotations.Note
+		}
+		if annotations.Tags != nil {
+			tx.Tags = annotations.Tags
+		}
+		writeJSON(w, http.StatusOK, tx)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+func (s *Server) serveReceipts(w http.ResponseWriter, r *http.Request, tx *bento.Transaction) {
+	switch r.Method {
+	case "GET":
+		receipts := []bento.Receipt{}
+		for _, state := range s.receipts[tx.CardTransactionId] {
+			receipts = append(receipts, state.receipt)
+		}
+		writeJSON(w, http.StatusOK, receipts)
+	case "POST":
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_RECEIPT", "Expected a multipart form with a file: %v", err)
+			return
+		}
+		defer file.Close()
+		data, err := ioutil.ReadAll(file)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_RECEIPT", "Unable to read receipt: %v", err)
+			return
+		}
+		contentType := header.Header.Get("Content-Type")
+		if contentType != "application/pdf" && !strings.HasPrefix(contentType, "image/") {
+			writeError(w, http.StatusBadRequest, "UNSUPPORTED_RECEIPT_TYPE", "Receipts must be images or PDFs, not %q", contentType)
+			return
+		}
+
+		state := &receiptState{
+			receipt: bento.Receipt{
+				ReceiptId:         s.newId(),
+				CardTransactionId: tx.CardTransactionId,
+				FileName:          header.Filename,
+				ContentType:       contentType,
+				Size:              int64(len(data)),
+				CreatedOn:         s.now(),
+			},
+			data: data,
+		}
+		s.receipts[tx.CardTransactionId] = append(s.receipts[tx.CardTransactionId], state)
+		writeJSON(w, http.StatusOK, state.receipt)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+func (s *Server) serveReceipt(w http.ResponseWriter, r *http.Request, tx *bento.Transaction, id string) {
+	receipts := s.receipts[tx.CardTransactionId]
+	index := -1
+	for i, state := range receipts {
+		if strconv.FormatInt(state.receipt.ReceiptId, 10) == id {
+			index = i
+		}
+	}
+	if index < 0 {
+		writeError(w, http.StatusNotFound, "RECEIPT_NOT_FOUND", "Receipt %s not found", id)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", receipts[index].receipt.ContentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(receipts[index].data)
+	case "DELETE":
+		s.receipts[tx.CardTransactionId] = append(receipts[:index:index], receipts[index+1:]...)
+		writeJSON(w, http.StatusOK, receipts[index].receipt)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+func (s *Server) serveUsers(w http.ResponseWriter, r *http.Request, path []string) {
+	if len(path) == 0 {
+		switch r.Method {
+		case "GET":
+			users := []bento.User{}
+			for _, id := range s.userOrder {
+				if user := s.users[id]; !user.Deleted {
+					users = append(users, *user)
+				}
+			}
+			writeJSON(w, http.StatusOK, users)
+		case "POST":
+			var user bento.User
+			if !decode(w, r, &user) || !validUser(w, user) {
+				return
+			}
+			user.UserId = 0
+			user.Deleted = false
+			writeJSON(w, http.StatusOK, s.addUser(user))
+		default:
+			methodNotAllowed(w, r)
+		}
+		return
+	}
+
+	userId, err := strconv.ParseInt(path[0], 10, 64)
+	if err != nil {
+		notFound(w, r)
+		return
+	}
+	user, ok := s.users[userId]
+	if !ok {
+		writeError(w, http.StatusNotFound, "USER_NOT_FOUND", "User %d not found", userId)
+		return
+	}
+	if len(path) >= 2 && path[1] == "addresses" {
+		addresses := s.addresses[userId]
+		s.serveAddresses(w, r, &addresses, path[2:])
+		s.addresses[userId] = addresses
+		return
+	}
+	if len(path) != 1 {
+		notFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, user)
+	case "PUT":
+		if user.Deleted {
+			writeError(w, http.StatusBadRequest, "USER_DELETED", "User %d is deleted", userId)
+			return
+		}
+		updated := *user
+		if !decode(w, r, &updated) || !validUser(w, updated) {
+			return
+		}
+		// Read-only fields.
+		updated.UserId = user.UserId
+		updated.Created = user.Created
+		updated.Deleted = user.Deleted
+		updated.BentoType = user.BentoType
+		*user = updated
+		writeJSON(w, http.StatusOK, user)
+	case "DELETE":
+		user.Deleted = true
+		writeJSON(w, http.StatusOK, user)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// serveAddresses serves a list of addresses, which are deactivated rather
+// than deleted.
+func (s *Server) serveAddresses(w http.ResponseWriter, r *http.Request, addresses *[]bento.Address, path []string) {
+	if len(path) == 0 {
+		switch r.Method {
+		case "GET":
+			writeJSON(w, http.StatusOK, append([]bento.Address{}, *addresses...))
+		case "POST":
+			var address bento.Address
+			if !decode(w, r, &address) || !validAddress(w, address) {
+				return
+			}
+			address.Id = s.newId()
+			*addresses = append(*addresses, address)
+			writeJSON(w, http.StatusOK, address)
+		default:
+			methodNotAllowed(w, r)
+		}
+		return
+	}
+
+	addressId, err := strconv.ParseInt(path[0], 10, 64)
+	if err != nil || len(path) != 1 {
+		notFound(w, r)
+		return
+	}
+	var address *bento.Address
+	for i := range *addresses {
+		if (*addresses)[i].Id == addressId {
+			address = &(*addresses)[i]
+		}
+	}
+	if address == nil {
+		writeError(w, http.StatusNotFound, "ADDRESS_NOT_FOUND", "Address %d not found", addressId)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, address)
+	case "PUT":
+		updated := *address
+		if !decode(w, r, &updated) || !validAddress(w, updated) {
+			return
+		}
+		updated.Id = address.Id
+		updated.AddressType = address.AddressType
+		*address = updated
+		writeJSON(w, http.StatusOK, address)
+	case "DELETE":
+		address.Active = false
+		writeJSON(w, http.StatusOK, address)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// validAddress checks that address is complete, and writes an error if not.
+func validAddress(w http.ResponseWriter, address bento.Address) bool {
+	if address.Street == "" || address.City == "" || address.State == "" || address.ZipCode == "" {
+		writeError(w, http.StatusBadRequest, "INVALID_ADDRESS", "Addresses need a street, city, state and zip code")
+		return false
+	}
+	return true
+}
+
+// validUser checks that user has the details the API requires, and writes
+// an error if not.
+func validUser(w http.ResponseWriter, user bento.User) bool {
+	if user.FirstName == "" || user.LastName == "" || user.Email == "" {
+		writeError(w, http.StatusBadRequest, "INVALID_USER", "Users need a first name, last name and email")
+		return false
+	}
+	return true
+}
+
+// transactionFilter holds the filters GET /transactions supports. It is
+// implemented here rather than with bento.TransactionQuery, so that the
+// fake doesn't share the client's bugs.
+type transactionFilter struct {
+	dateStart, dateEnd   int64
+	cardIds, categoryIds []int64
+}
+
+func (f transactionFilter) matches(tx bento.Transaction) bool {
+	if f.dateStart != 0 && tx.TransactionDate < f.dateStart {
+		return false
+	}
+	if f.dateEnd != 0 && tx.TransactionDate > f.dateEnd {
+		return false
+	}
+	if f.cardIds != nil {
+		if tx.Card == nil || !containsId(f.cardIds, tx.Card.CardId) {
+			return false
+		}
+	}
+	if f.categoryIds != nil {
+		if tx.Category == nil || !containsId(f.categoryIds, tx.Category.TransactionCategoryId) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsId(ids []int64, id int64) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIds parses a comma separated list of IDs. An empty list is nil.
+func parseIds(value string) ([]int64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var ids []int64
+	for _, field := range strings.Split(value, ",") {
+		id, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
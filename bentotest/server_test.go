@@ -0,0 +1,892 @@
+package bentotest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	bento "github.com/knusbaum/bento-go"
+	"github.com/knusbaum/bento-go/bentotest"
+)
+
+// newSession starts a fake server and logs in to it. The caller must close
+// the server.
+func newSession(t *testing.T) (*bentotest.Server, *bento.Session) {
+	server := bentotest.NewServer()
+	session, err := server.Session()
+	if err != nil {
+		server.Close()
+		t.Fatalf("Failed to log in to fake server: %s", err)
+	}
+	return server, session
+}
+
+func TestLogin(t *testing.T) {
+	t.Log("TestLogin")
+	server := bentotest.NewServer()
+	defer server.Close()
+
+	_, err := bento.GetTestSession("wrong", "keys", bento.WithBaseURL(server.URL))
+	if !bento.IsUnauthorized(err) {
+		t.Errorf("Expected login with bad keys to be unauthorized, got: %v", err)
+	}
+}
+
+func TestGetBusiness(t *testing.T) {
+	t.Log("TestGetBusiness")
+	server, session := newSession(t)
+	defer server.Close()
+
+	business, err := session.GetBusiness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if business.CompanyName != "Test Company Inc" || len(business.Addresses) != 1 {
+		t.Errorf("Unexpected business: %+v", business)
+	}
+}
+
+func TestCardLifecycle(t *testing.T) {
+	t.Log("TestCardLifecycle")
+	server, session := newSession(t)
+	defer server.Close()
+
+	card, err := session.NewCard(bento.EMPLOYEE_CARD, "Travel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.CardId == 0 || card.LifecycleStatus != "CREATED" || card.Status != bento.STATUS_TURNED_OFF {
+		t.Fatalf("Unexpected new card: %+v", card)
+	}
+
+	cards, err := session.GetCards()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 1 || cards[0].CardId != card.CardId {
+		t.Fatalf("Expected the new card to be listed, got %+v", cards)
+	}
+
+	lastFour := card.LastFour
+	if _, err := card.Activate("0000"); !bento.IsValidation(err) {
+		t.Errorf("Expected activation with the wrong last four to fail, got: %v", err)
+	}
+	card, err = card.Activate(lastFour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.LifecycleStatus != "ACTIVATED" || card.Status != bento.STATUS_TURNED_ON {
+		t.Errorf("Expected activated card to be turned on: %+v", card)
+	}
+
+	card.Alias = "Travel 2019"
+	card, err = card.Put()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := server.Card(card.CardId); stored.Alias != "Travel 2019" {
+		t.Errorf(`Expected stored alias "Travel 2019", got %q`, stored.Alias)
+	}
+
+	card, err = card.TurnOff()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pan, err := card.GetPanAndCvv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := server.PanAndCvv(card.CardId)
+	if *pan != expected || pan.Pan[12:] != card.LastFour {
+		t.Errorf("Expected %+v ending in %s, got %+v", expected, card.LastFour, pan)
+	}
+
+	reissued, err := card.Reissue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reissued.LastFour == card.LastFour || reissued.LifecycleStatus != "CREATED" {
+		t.Errorf("Expected reissued card to have a new number: %+v", reissued)
+	}
+
+	deleted, err := reissued.Delete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted.Status != bento.STATUS_CANCELED {
+		t.Errorf("Expected deleted card to be canceled: %+v", deleted)
+	}
+	if _, err := deleted.TurnOn(); !bento.IsValidation(err) {
+		t.Errorf("Expected updating a canceled card to fail, got: %v", err)
+	}
+}
+
+func TestCardNotFound(t *testing.T) {
+	t.Log("TestCardNotFound")
+	server, session := newSession(t)
+	defer server.Close()
+
+	if _, err := session.GetCard(42); !bento.IsNotFound(err) {
+		t.Errorf("Expected IsNotFound, got: %v", err)
+	}
+}
+
+func TestNewCategoryCardRequiresCategory(t *testing.T) {
+	t.Log("TestNewCategoryCardRequiresCategory")
+	server, session := newSession(t)
+	defer server.Close()
+
+	if _, err := session.NewCard(bento.CATEGORY_CARD, "Meals"); err == nil {
+		t.Error("Expected a CategoryCard without a category to be rejected")
+	}
+	if len(server.Cards()) != 0 {
+		t.Errorf("Expected no card to be created, got %+v", server.Cards())
+	}
+}
+
+func TestBillingAddress(t *testing.T) {
+	t.Log("TestBillingAddress")
+	server, session := newSession(t)
+	defer server.Close()
+	card, err := session.GetCard(server.AddCard(bento.Card{Type: bento.EMPLOYEE_CARD}).CardId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := card.GetBillingAddress(); !bento.IsNotFound(err) {
+		t.Errorf("Expected no billing address yet, got: %v", err)
+	}
+
+	address, err := card.SetBillingAddress(&bento.Address{
+		Street: "1 Main Street", City: "Austin", State: "TX", ZipCode: "78701",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	address.City = "Dallas"
+	if _, err := card.UpdateBillingAddress(address); err != nil {
+		t.Fatal(err)
+	}
+	address, err = card.GetBillingAddress()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if address.City != "Dallas" || !address.Active {
+		t.Errorf("Unexpected billing address: %+v", address)
+	}
+}
+
+func TestTransactions(t *testing.T) {
+	t.Log("TestTransactions")
+	server, session := newSession(t)
+	defer server.Close()
+	card := server.AddCard(bento.Card{Type: bento.EMPLOYEE_CARD})
+	server.AddTransaction(bento.Transaction{Amount: 12.5, Card: &bento.Card{CardId: card.CardId}})
+	server.AddTransaction(bento.Transaction{Amount: 7.5})
+
+	transactions, err := session.GetTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transactions.Size != 2 || transactions.Amount != 20 || len(transactions.CardTransactions) != 2 {
+		t.Fatalf("Unexpected transactions: %+v", transactions)
+	}
+	if transactions.CardTransactions[0].Card.LastFour != card.LastFour {
+		t.Errorf("Expected the transaction's card to be filled in: %+v", transactions.CardTransactions[0].Card)
+	}
+}
+
+func TestExpiredToken(t *testing.T) {
+	t.Log("TestExpiredToken")
+	server, session := newSession(t)
+	defer server.Close()
+
+	server.ExpireTokens()
+	if _, err := session.GetCards(); err != nil {
+		t.Errorf("Expected the session to log in again, got: %v", err)
+	}
+}
+
+func TestTransactionPages(t *testing.T) {
+	t.Log("TestTransactionPages")
+	server, session := newSession(t)
+	defer server.Close()
+	for i := 0; i < 5; i++ {
+		server.AddTransaction(bento.Transaction{Amount: float64(i)})
+	}
+
+	page, err := session.GetTransactionsPage(bento.PageOptions{Offset: 3, Size: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Size != 5 || len(page.CardTransactions) != 2 || page.CardTransactions[0].Amount != 3 {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+
+	count := 0
+	for tx, err := range session.AllTransactions(context.Background(), 2) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.Amount != float64(count) {
+			t.Errorf("Expected transaction %d, got %+v", count, tx)
+		}
+		count++
+	}
+	if count != 5 {
+		t.Errorf("Expected 5 transactions, got %d", count)
+	}
+}
+
+func TestAllTransactionsCappedPageSize(t *testing.T) {
+	t.Log("TestAllTransactionsCappedPageSize")
+	server, session := newSession(t)
+	defer server.Close()
+	for i := 0; i < 7; i++ {
+		server.AddTransaction(bento.Transaction{Amount: float64(i)})
+	}
+	server.SetMaxPageSize(3)
+
+	page, err := session.GetTransactionsPage(bento.PageOptions{Size: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Size != 7 || len(page.CardTransactions) != 3 {
+		t.Fatalf("Expected a capped page of 3, got %+v", page)
+	}
+
+	count := 0
+	for tx, err := range session.AllTransactions(context.Background(), 5) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.Amount != float64(count) {
+			t.Errorf("Expected transaction %d, got %+v", count, tx)
+		}
+		count++
+	}
+	if count != 7 {
+		t.Errorf("Expected all 7 transactions despite the capped pages, got %d", count)
+	}
+}
+
+func TestQueryTransactions(t *testing.T) {
+	t.Log("TestQueryTransactions")
+	server, session := newSession(t)
+	defer server.Close()
+	travel := server.AddCard(bento.Card{Type: bento.EMPLOYEE_CARD, Alias: "Travel"})
+	office := server.AddCard(bento.Card{Type: bento.EMPLOYEE_CARD, Alias: "Office"})
+	for i := 0; i < 6; i++ {
+		card := travel
+		if i%2 == 1 {
+			card = office
+		}
+		server.AddTransaction(bento.Transaction{
+			Amount:          float64(i * 10),
+			TransactionDate: int64(1000 + i),
+			Card:            &bento.Card{CardId: card.CardId},
+			Payee:           &bento.Payee{Name: fmt.Sprintf("Payee %d", i)},
+		})
+	}
+
+	min := 20.0
+	query := bento.TransactionQuery{
+		PageOptions:         bento.PageOptions{Size: 2},
+		TransactionDateFrom: 1001,
+		CardIds:             []int64{travel.CardId},
+		MinAmount:           &min,
+	}
+	page, err := session.QueryTransactions(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The card and date filters are applied by the server, which finds 2
+	// transactions; the amount filter is applied by the client.
+	if page.Size != 2 || len(page.CardTransactions) != 2 || page.CardTransactions[0].Amount != 20 {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+
+	var amounts []float64
+	query.PageOptions = bento.PageOptions{Size: 1}
+	query.CardIds = nil
+	query.PayeeName = "payee 5"
+	for tx, err := range session.QueryAllTransactions(context.Background(), query) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		amounts = append(amounts, tx.Amount)
+	}
+	if len(amounts) != 1 || amounts[0] != 50 {
+		t.Errorf("Expected only transaction 5, got %v", amounts)
+	}
+}
+
+func TestCardTransactions(t *testing.T) {
+	t.Log("TestCardTransactions")
+	server, session := newSession(t)
+	defer server.Close()
+	travel := server.AddCard(bento.Card{Type: bento.EMPLOYEE_CARD, Alias: "Travel", VirtualCard: true})
+	office := server.AddCard(bento.Card{Type: bento.EMPLOYEE_CARD, Alias: "Office"})
+	for i := 0; i < 5; i++ {
+		cardId := travel.CardId
+		if i == 2 {
+			cardId = office.CardId
+		}
+		server.AddTransaction(bento.Transaction{Amount: float64(i), Card: &bento.Card{CardId: cardId}})
+	}
+
+	card, err := session.GetCard(travel.CardId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := card.Transactions(bento.TransactionQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Size != 4 || len(page.CardTransactions) != 4 {
+		t.Errorf("Expected the card's 4 transactions, got %+v", page)
+	}
+
+	count := 0
+	for tx, err := range card.AllTransactions(context.Background(), bento.TransactionQuery{PageOptions: bento.PageOptions{Size: 3}}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx.Card.CardId != travel.CardId {
+			t.Errorf("Expected only the card's transactions, got %+v", tx)
+		}
+		count++
+	}
+	if count != 4 {
+		t.Errorf("Expected 4 transactions, got %d", count)
+	}
+
+	// The card on a transaction is bound to the session.
+	_, err = page.CardTransactions[0].Card.TurnOff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated, _ := server.Card(travel.CardId); updated.Status != "TURNED_OFF" {
+		t.Errorf("Expected the card to be turned off, got %s", updated.Status)
+	}
+}
+
+func TestAnnotateTransaction(t *testing.T) {
+	t.Log("TestAnnotateTransaction")
+	server, session := newSession(t)
+	defer server.Close()
+	seeded := server.AddTransaction(bento.Transaction{Amount: 12.5, Note: "Lunch", Tags: []string{"meals"}})
+
+	tx, err := session.GetTransaction(seeded.CardTransactionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Note != "Lunch" || len(tx.Tags) != 1 || tx.Amount != 12.5 {
+		t.Errorf("Unexpected transaction: %+v", tx)
+	}
+
+	tx.Note = "Client lunch"
+	tx.Tags = append(tx.Tags, "client:acme")
+	tx.Amount = 0
+	updated, err := tx.Put()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Note != "Client lunch" || len(updated.Tags) != 2 || updated.Amount != 12.5 {
+		t.Errorf("Unexpected transaction: %+v", updated)
+	}
+	stored, _ := server.Transaction(seeded.CardTransactionId)
+	if stored.Note != "Client lunch" || len(stored.Tags) != 2 || stored.Amount != 12.5 {
+		t.Errorf("Expected only the note and tags to change, got %+v", stored)
+	}
+
+	// Clearing the annotations.
+	updated.Note = ""
+	updated.Tags = nil
+	_, err = updated.Put()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ = server.Transaction(seeded.CardTransactionId)
+	if stored.Note != "" || len(stored.Tags) != 0 {
+		t.Errorf("Expected the note and tags to be cleared, got %+v", stored)
+	}
+
+	_, err = session.GetTransaction(1)
+	if !bento.IsNotFound(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestReceipts(t *testing.T) {
+	t.Log("TestReceipts")
+	server, session := newSession(t)
+	defer server.Close()
+	seeded := server.AddTransaction(bento.Transaction{Amount: 42})
+
+	tx, err := session.GetTransaction(seeded.CardTransactionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipt, err := tx.UploadReceipt("dinner.pdf", strings.NewReader("%PDF-1.4 dinner"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if receipt.FileName != "dinner.pdf" || receipt.ContentType != "application/pdf" || receipt.Size != 15 {
+		t.Errorf("Unexpected receipt: %+v", receipt)
+	}
+
+	receipts, err := tx.Receipts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipts) != 1 || receipts[0].ReceiptId != receipt.ReceiptId {
+		t.Errorf("Unexpected receipts: %+v", receipts)
+	}
+
+	var buf bytes.Buffer
+	err = tx.DownloadReceipt(receipt.ReceiptId, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "%PDF-1.4 dinner" {
+		t.Errorf("Unexpected receipt contents: %q", buf.String())
+	}
+
+	err = tx.DeleteReceipt(receipt.ReceiptId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipts, err = tx.Receipts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipts) != 0 {
+		t.Errorf("Expected the receipt to be deleted, got %+v", receipts)
+	}
+	err = tx.DownloadReceipt(receipt.ReceiptId, &buf)
+	if !bento.IsNotFound(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestAttachReceipts(t *testing.T) {
+	t.Log("TestAttachReceipts")
+	server, session := newSession(t)
+	defer server.Close()
+	lunch := server.AddTransaction(bento.Transaction{Amount: 15})
+	taxi := server.AddTransaction(bento.Transaction{Amount: 30})
+
+	dir := t.TempDir()
+	files := map[string]string{
+		fmt.Sprintf("%d.pdf", lunch.CardTransactionId):        "%PDF-1.4 lunch",
+		fmt.Sprintf("%d-front.jpg", taxi.CardTransactionId):   "front",
+		fmt.Sprintf("%d-back.jpg", taxi.CardTransactionId):    "back",
+		fmt.Sprintf("%d.pdf", taxi.CardTransactionId+1000000): "%PDF-1.4 missing",
+		"notes.txt": "not a receipt",
+		// A phone scan named after the time it was taken, which happens
+		// to start with a transaction's ID.
+		fmt.Sprintf("%d_103000.jpg", taxi.CardTransactionId): "scan",
+	}
+	for name, contents := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	attachments, err := session.AttachReceipts(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attachments) != 4 {
+		t.Fatalf("Expected 4 files to be attached, got %+v", attachments)
+	}
+	failed := 0
+	for _, attachment := range attachments {
+		if attachment.Err != nil {
+			failed++
+			if !bento.IsNotFound(attachment.Err) || attachment.CardTransactionId != taxi.CardTransactionId+1000000 {
+				t.Errorf("Unexpected failure: %+v", attachment)
+			}
+		}
+	}
+	if failed != 1 {
+		t.Errorf("Expected only the receipt for a missing transaction to fail, got %+v", attachments)
+	}
+
+	tx, err := session.GetTransaction(taxi.CardTransactionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	receipts, err := tx.Receipts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(receipts) != 2 {
+		t.Errorf("Expected both taxi receipts, got %+v", receipts)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	t.Log("TestUsers")
+	server, session := newSession(t)
+	defer server.Close()
+	card := server.AddCard(bento.Card{Type: bento.EMPLOYEE_CARD, Alias: "Travel"})
+
+	user, err := session.NewUser(bento.User{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.UserId == 0 || user.Created == 0 {
+		t.Errorf("Expected the server to fill in the user's ID, got %+v", user)
+	}
+	_, err = session.NewUser(bento.User{FirstName: "Nobody"})
+	if !bento.IsValidation(err) {
+		t.Errorf("Expected a validation error for a user without an email, got %v", err)
+	}
+
+	user.Phone = "5555550100"
+	user, err = user.Put()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := server.User(user.UserId); stored.Phone != "5555550100" {
+		t.Errorf("Expected the phone number to be saved, got %+v", stored)
+	}
+
+	updated, err := user.AssignCard(&bento.Card{CardId: card.CardId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.User.UserId != user.UserId || updated.User.Email != "ada@example.com" || updated.Alias != "Travel" {
+		t.Errorf("Expected the card to be assigned to the user, got %+v", updated)
+	}
+
+	users, err := session.GetUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 {
+		t.Errorf("Expected 1 user, got %+v", users)
+	}
+	deleted, err := users[0].Delete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !deleted.Deleted {
+		t.Error(`Expected the user to be deleted`)
+	}
+	users, err = session.GetUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 0 {
+		t.Errorf("Expected deleted users not to be listed, got %+v", users)
+	}
+	other := server.AddCard(bento.Card{Type: bento.EMPLOYEE_CARD, Alias: "Office"})
+	_, err = user.AssignCard(&bento.Card{CardId: other.CardId})
+	if !bento.IsValidation(err) {
+		t.Errorf("Expected cards not to be assignable to deleted users, got %v", err)
+	}
+}
+
+func TestCategories(t *testing.T) {
+	t.Log("TestCategories")
+	server, session := newSession(t)
+	defer server.Close()
+	card := server.AddCard(bento.Card{Type: bento.EMPLOYEE_CARD, Alias: "Travel"})
+
+	categories, err := session.GetCategories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hotels, ok := categories.ByMcc(7011); !ok || hotels.Name != "Hotels" {
+		t.Errorf("Expected MCC 7011 to be a hotel, got %+v", hotels)
+	}
+
+	allowed, err := categories.Named("Airlines", "Hotels")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := session.GetCard(card.CardId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.AllowedCategoriesActive = true
+	c.AllowedCategories = allowed
+	_, err = c.Put()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := server.Card(card.CardId)
+	if len(stored.AllowedCategories) != 2 || stored.AllowedCategories[1].TransactionCategoryId != 2 {
+		t.Errorf("Unexpected allowed categories: %+v", stored.AllowedCategories)
+	}
+
+	// The catalog is cached until it is refreshed.
+	server.SetCategories([]bento.Category{{TransactionCategoryId: 99, Name: "Everything"}})
+	if categories, _ := session.GetCategories(); len(categories) != 6 {
+		t.Errorf("Expected the cached catalog, got %+v", categories)
+	}
+	categories, err = session.RefreshCategories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := categories.ByName("everything"); !ok || len(categories) != 1 {
+		t.Errorf("Expected the new catalog, got %+v", categories)
+	}
+}
+
+func TestAddresses(t *testing.T) {
+	t.Log("TestAddresses")
+	server, session := newSession(t)
+	defer server.Close()
+
+	address, err := session.NewBusinessAddress(&bento.Address{
+		Street: "500 Congress Avenue", City: "Austin", State: "TX", ZipCode: "78701",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !address.Active || address.AddressType != bento.BUSINESS_ADDRESS {
+		t.Errorf("Unexpected address: %+v", address)
+	}
+	address.ZipCode = "78701-1234"
+	if _, err := session.UpdateBusinessAddress(address); err != nil {
+		t.Fatal(err)
+	}
+
+	// An edit that doesn't round-trip Active leaves the address active.
+	updated, err := session.UpdateBusinessAddress(&bento.Address{
+		Id: address.Id, Street: "501 Congress Avenue", City: "Austin", State: "TX", ZipCode: "78701-1234",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.Active || updated.Street != "501 Congress Avenue" {
+		t.Errorf("Expected the updated address to stay active: %+v", updated)
+	}
+	if _, err := session.DeactivateBusinessAddress(address.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	addresses, err := session.GetBusinessAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addresses) != 2 || addresses[1].ZipCode != "78701-1234" || addresses[1].Active {
+		t.Errorf("Unexpected addresses: %+v", addresses)
+	}
+	business, err := session.GetBusiness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(business.Addresses) != 2 {
+		t.Errorf("Expected the business to have the new address, got %+v", business.Addresses)
+	}
+
+	user, err := session.NewUser(bento.User{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	home, err := user.NewAddress(&bento.Address{
+		Street: "1 Main Street", City: "Portland", State: "OR", ZipCode: "97201",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if home.AddressType != bento.USER_ADDRESS {
+		t.Errorf("Unexpected address: %+v", home)
+	}
+	home.Street = "2 Main Street"
+	if _, err := user.UpdateAddress(home); err != nil {
+		t.Fatal(err)
+	}
+	addresses, err = user.GetAddresses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addresses) != 1 || addresses[0].Street != "2 Main Street" {
+		t.Errorf("Unexpected addresses: %+v", addresses)
+	}
+	_, err = user.DeactivateAddress(home.Id + 1)
+	if !bento.IsNotFound(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
+
+func TestSessionApplication(t *testing.T) {
+	t.Log("TestSessionApplication")
+	server, session := newSession(t)
+	defer server.Close()
+
+	app := session.Application()
+	if app.ApiApplicationId != 1 || app.Name != "bentotest" || app.AccessKey != bentotest.AccessKey {
+		t.Errorf("Unexpected application: %+v", app)
+	}
+	if app.Business.CompanyName != "Test Company Inc" {
+		t.Errorf("Expected the application's business, got %+v", app.Business)
+	}
+}
+
+func TestNewCardOptions(t *testing.T) {
+	t.Log("TestNewCardOptions")
+	server, session := newSession(t)
+	defer server.Close()
+	user := server.AddUser(bento.User{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"})
+
+	card, err := session.NewCard(bento.CATEGORY_CARD, "Meals",
+		bento.Virtual(),
+		bento.ForUser(user.UserId),
+		bento.InCategory(3),
+		bento.LimitSpending(500, bento.PERIOD_MONTH),
+		bento.AllowDays("MONDAY", "FRIDAY"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !card.VirtualCard || card.Status != bento.STATUS_TURNED_ON {
+		t.Errorf("Expected a virtual card that is turned on, got %+v", card)
+	}
+	if card.User.Email != "ada@example.com" || card.TransactionCategoryId != 3 {
+		t.Errorf("Expected the card to belong to the user and category, got %+v", card)
+	}
+	if card.SpendingLimit.Amount != 500 || !card.AllowedDaysActive || len(card.AllowedDays) != 2 {
+		t.Errorf("Expected the limits to be set, got %+v", card)
+	}
+
+	_, err = session.NewCard(bento.EMPLOYEE_CARD, "Nobody's", bento.ForUser(user.UserId+1))
+	if !bento.IsValidation(err) {
+		t.Errorf("Expected an unknown user to be rejected, got %v", err)
+	}
+}
+
+func TestCardUpdateDoesNotClobber(t *testing.T) {
+	t.Log("TestCardUpdateDoesNotClobber")
+	server, session := newSession(t)
+	defer server.Close()
+	seeded := server.AddCard(bento.Card{Type: bento.EMPLOYEE_CARD, Alias: "Travel"})
+
+	// Two processes fetch the card and change different fields.
+	first, err := session.GetCard(seeded.CardId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := session.GetCard(seeded.CardId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.Update(bento.SetAlias("Conferences")); err != nil {
+		t.Fatal(err)
+	}
+	updated, err := second.Update(bento.SetLimit(2000, bento.PERIOD_MONTH), bento.SetAllowedDays("MONDAY"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if updated.Alias != "Conferences" || updated.SpendingLimit.Amount != 2000 || len(updated.AllowedDays) != 1 {
+		t.Errorf("Expected both updates to survive, got %+v", updated)
+	}
+	stored, _ := server.Card(seeded.CardId)
+	if stored.Alias != "Conferences" || stored.SpendingLimit.Amount != 2000 {
+		t.Errorf("Expected both updates to be stored, got %+v", stored)
+	}
+}
+
+func TestPutConflictDetection(t *testing.T) {
+	t.Log("TestPutConflictDetection")
+	server := bentotest.NewServer()
+	defer server.Close()
+	session, err := server.Session(bento.WithConflictDetection())
+	if err != nil {
+		t.Fatal(err)
+	}
+	seeded := server.AddCard(bento.Card{Type: bento.EMPLOYEE_CARD, Alias: "Travel"})
+
+	// The web UI and the automation both start from the same version.
+	ui, _ := session.GetCard(seeded.CardId)
+	automation, _ := session.GetCard(seeded.CardId)
+	ui.Alias = "Conferences"
+	if _, err := ui.Put(); err != nil {
+		t.Fatal(err)
+	}
+
+	automation.SpendingLimit = bento.SpendingLimit{Active: true, Amount: 500, Period: bento.PERIOD_DAY}
+	_, err = automation.Put()
+	var conflict *bento.ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("Expected a ConflictError, got %v", err)
+	}
+	if conflict.Theirs.Alias != "Conferences" || conflict.Mine.SpendingLimit.Amount != 500 {
+		t.Errorf("Unexpected versions in conflict: %+v, %+v", conflict.Mine, conflict.Theirs)
+	}
+	if stored, _ := server.Card(seeded.CardId); stored.SpendingLimit.Active {
+		t.Error(`Expected the conflicting Put not to be saved`)
+	}
+
+	saved, err := automation.PutWithMerge(func(mine, theirs *bento.Card) (*bento.Card, error) {
+		merged := *theirs
+		merged.SpendingLimit = mine.SpendingLimit
+		return &merged, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Alias != "Conferences" || saved.SpendingLimit.Amount != 500 {
+		t.Errorf("Expected both changes to be saved, got %+v", saved)
+	}
+}
+
+func TestCardOperationsRefreshInPlace(t *testing.T) {
+	t.Log("TestCardOperationsRefreshInPlace")
+	server := bentotest.NewServer()
+	defer server.Close()
+	session, err := server.Session(bento.WithRefreshInPlace())
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := session.NewCard(bento.EMPLOYEE_CARD, "Travel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastFour := card.LastFour
+
+	if _, err := card.Activate("0000"); !bento.IsValidation(err) {
+		t.Errorf("Expected activation with the wrong last four to fail, got: %v", err)
+	}
+	if card.LastFour != lastFour || card.LifecycleStatus != "CREATED" {
+		t.Errorf("Expected a failed activation to leave the card alone, got %+v", card)
+	}
+
+	activated, err := card.Activate(lastFour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.LifecycleStatus != "ACTIVATED" || card.Status != bento.STATUS_TURNED_ON || card.UpdatedOn != activated.UpdatedOn {
+		t.Errorf("Expected the card to be refreshed after activation, got %+v", card)
+	}
+
+	if _, err := card.TurnOff(); err != nil {
+		t.Fatal(err)
+	}
+	if card.Status != bento.STATUS_TURNED_OFF {
+		t.Errorf("Expected the card to be turned off in place, got %+v", card)
+	}
+
+	stale := *card
+	if _, err := card.Delete(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stale.TurnOn(); !bento.IsValidation(err) {
+		t.Errorf("Expected turning on a canceled card to fail, got: %v", err)
+	}
+	if stale.Status != bento.STATUS_TURNED_OFF {
+		t.Errorf("Expected a failed TurnOn to leave the card alone, got %+v", stale)
+	}
+}
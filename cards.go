@@ -0,0 +1,352 @@
+package bento
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Days of the week, for Card.AllowedDays.
+var weekdays = map[string]bool{
+	"MONDAY":    true,
+	"TUESDAY":   true,
+	"WEDNESDAY": true,
+	"THURSDAY":  true,
+	"FRIDAY":    true,
+	"SATURDAY":  true,
+	"SUNDAY":    true,
+}
+
+// NewCardRequest describes a card to create with CreateCard. It holds every
+// field of a Card that can be set when the card is created.
+type NewCardRequest struct {
+	Type  CardType
+	Alias string
+	// VirtualCard creates a virtual card, which can be used straight away,
+	// instead of a physical one.
+	VirtualCard bool
+	// UserId is the user the card is issued to, if any.
+	UserId int64
+	// SpendingLimit, if set, limits how much can be spent with the card.
+	SpendingLimit *SpendingLimit
+	// AllowedDays, if not empty, restricts the card to these days of the
+	// week, e.g. "MONDAY".
+	AllowedDays []string
+	// AllowedCategories, if not empty, restricts the card to these
+	// transaction categories. Only their TransactionCategoryIds are used.
+	AllowedCategories []Category
+	// TransactionCategoryId is the category of a CATEGORY_CARD, and must
+	// be zero for other types of card.
+	TransactionCategoryId int64
+}
+
+// CardOption sets part of a NewCardRequest, for use with NewCard.
+type CardOption func(*NewCardRequest)
+
+// Virtual creates a virtual card.
+func Virtual() CardOption {
+	return func(request *NewCardRequest) {
+		request.VirtualCard = true
+	}
+}
+
+// ForUser issues the card to the user with the given ID.
+func ForUser(userId int64) CardOption {
+	return func(request *NewCardRequest) {
+		request.UserId = userId
+	}
+}
+
+// LimitSpending limits spending on the card to amount per period. Use
+// CreateCard to set a PERIOD_CUSTOM limit, which also needs dates.
+func LimitSpending(amount float64, period Period) CardOption {
+	return func(request *NewCardRequest) {
+		request.SpendingLimit = &SpendingLimit{Active: true, Amount: amount, Period: period}
+	}
+}
+
+// AllowDays restricts the card to the given days of the week, e.g.
+// "MONDAY".
+func AllowDays(days ...string) CardOption {
+	return func(request *NewCardRequest) {
+		request.AllowedDays = days
+	}
+}
+
+// AllowCategories restricts the card to the given transaction categories.
+func AllowCategories(categories ...Category) CardOption {
+	return func(request *NewCardRequest) {
+		request.AllowedCategories = categories
+	}
+}
+
+// InCategory sets the category of a CATEGORY_CARD.
+func InCategory(categoryId int64) CardOption {
+	return func(request *NewCardRequest) {
+		request.TransactionCategoryId = categoryId
+	}
+}
+
+// Validate checks that the request describes a card the API will accept.
+// CreateCard validates requests before sending them.
+func (request *NewCardRequest) Validate() error {
+	var problems []string
+	switch request.Type {
+	case BUSINESS_OWNER_CARD, EMPLOYEE_CARD, CATEGORY_CARD:
+	default:
+		problems = append(problems, fmt.Sprintf("card type %q is not valid", request.Type))
+	}
+	if request.Type == CATEGORY_CARD && request.TransactionCategoryId == 0 {
+		problems = append(problems, "a CATEGORY_CARD requires a TransactionCategoryId")
+	}
+	if request.Type != CATEGORY_CARD && request.TransactionCategoryId != 0 {
+		problems = append(problems, "only a CATEGORY_CARD can have a TransactionCategoryId")
+	}
+	if request.SpendingLimit != nil {
+		problems = append(problems, limitProblems(*request.SpendingLimit)...)
+	}
+	problems = append(problems, restrictionProblems(request.AllowedDays, request.AllowedCategories)...)
+	if len(problems) > 0 {
+		return errors.New(fmt.Sprintf("Invalid card: %s", strings.Join(problems, ", ")))
+	}
+	return nil
+}
+
+func limitProblems(limit SpendingLimit) []string {
+	var problems []string
+	if limit.Amount <= 0 {
+		problems = append(problems, "the spending limit must be positive")
+	}
+	switch limit.Period {
+	case PERIOD_DAY, PERIOD_WEEK, PERIOD_MONTH:
+		if limit.CustomStartDate != 0 || limit.CustomEndDate != 0 {
+			problems = append(problems, "only a PERIOD_CUSTOM spending limit can have dates")
+		}
+	case PERIOD_CUSTOM:
+		if limit.CustomStartDate == 0 || limit.CustomEndDate <= limit.CustomStartDate {
+			problems = append(problems, "a PERIOD_CUSTOM spending limit needs a start date before its end date")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("spending limit period %q is not valid", limit.Period))
+	}
+	return problems
+}
+
+func restrictionProblems(days []string, categories []Category) []string {
+	var problems []string
+	for _, day := range days {
+		if !weekdays[day] {
+			problems = append(problems, fmt.Sprintf("%q is not a day of the week", day))
+		}
+	}
+	for _, category := range categories {
+		if category.TransactionCategoryId == 0 {
+			problems = append(problems, "allowed categories need a TransactionCategoryId")
+			break
+		}
+	}
+	return problems
+}
+
+// newCardBody is the body of a request to create a card.
+type newCardBody struct {
+	Type                    CardType       `json:"type"`
+	Alias                   string         `json:"alias,omitempty"`
+	VirtualCard             bool           `json:"virtualCard"`
+	User                    *userRef       `json:"user,omitempty"`
+	SpendingLimit           *SpendingLimit `json:"spendingLimit,omitempty"`
+	AllowedDaysActive       bool           `json:"allowedDaysActive"`
+	AllowedDays             []string       `json:"allowedDays,omitempty"`
+	AllowedCategoriesActive bool           `json:"allowedCategoriesActive"`
+	AllowedCategories       []categoryRef  `json:"allowedCategories,omitempty"`
+	TransactionCategoryId   int64          `json:"transactionCategoryId,omitempty"`
+}
+
+// userRef and categoryRef refer to a user or category by ID in a request.
+type userRef struct {
+	UserId int64 `json:"userId"`
+}
+
+type categoryRef struct {
+	TransactionCategoryId int64 `json:"transactionCategoryId"`
+}
+
+// CreateCard validates request and creates the card it describes.
+func (session *Session) CreateCard(request NewCardRequest) (*Card, error) {
+	return session.CreateCardContext(context.Background(), request)
+}
+
+// CreateCardContext is like CreateCard but uses ctx for the request.
+func (session *Session) CreateCardContext(ctx context.Context, request NewCardRequest) (*Card, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	body := newCardBody{
+		Type:                    request.Type,
+		Alias:                   request.Alias,
+		VirtualCard:             request.VirtualCard,
+		SpendingLimit:           request.SpendingLimit,
+		AllowedDaysActive:       len(request.AllowedDays) > 0,
+		AllowedDays:             request.AllowedDays,
+		AllowedCategoriesActive: len(request.AllowedCategories) > 0,
+		TransactionCategoryId:   request.TransactionCategoryId,
+	}
+	if request.UserId != 0 {
+		body.User = &userRef{UserId: request.UserId}
+	}
+	for _, category := range request.AllowedCategories {
+		body.AllowedCategories = append(body.AllowedCategories, categoryRef{category.TransactionCategoryId})
+	}
+
+	bs, err := session.call(ctx, "POST", "/cards", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var cardResp Card
+	err = json.Unmarshal(bs, &cardResp)
+	if err != nil {
+		return nil, err
+	}
+
+	cardResp.session = session
+	return &cardResp, nil
+}
+
+// CardUpdate changes part of a card, for use with Card.Update.
+type CardUpdate func(*Card)
+
+// SetAlias changes the card's alias.
+func SetAlias(alias string) CardUpdate {
+	return func(card *Card) {
+		card.Alias = alias
+	}
+}
+
+// SetLimit limits spending on the card to amount per period.
+func SetLimit(amount float64, period Period) CardUpdate {
+	return func(card *Card) {
+		card.SpendingLimit = SpendingLimit{Active: true, Amount: amount, Period: period}
+	}
+}
+
+// SetSpendingLimit replaces the card's spending limit, e.g. to set a
+// PERIOD_CUSTOM limit.
+func SetSpendingLimit(limit SpendingLimit) CardUpdate {
+	return func(card *Card) {
+		card.SpendingLimit = limit
+	}
+}
+
+// ClearLimit removes the card's spending limit.
+func ClearLimit() CardUpdate {
+	return func(card *Card) {
+		card.SpendingLimit = SpendingLimit{}
+	}
+}
+
+// SetAllowedDays restricts the card to the given days of the week. With no
+// days, the card can be used on any day.
+func SetAllowedDays(days ...string) CardUpdate {
+	return func(card *Card) {
+		card.AllowedDaysActive = len(days) > 0
+		card.AllowedDays = days
+	}
+}
+
+// SetAllowedCategories restricts the card to the given transaction
+// categories. With no categories, the card can be used in any category.
+func SetAllowedCategories(categories ...Category) CardUpdate {
+	return func(card *Card) {
+		card.AllowedCategoriesActive = len(categories) > 0
+		card.AllowedCategories = categories
+	}
+}
+
+// cardPatch returns the writable fields of updated that differ from card,
+// keyed by their JSON names.
+func cardPatch(card, updated *Card) map[string]interface{} {
+	patch := map[string]interface{}{}
+	if updated.Alias != card.Alias {
+		patch["alias"] = updated.Alias
+	}
+	if updated.SpendingLimit != card.SpendingLimit {
+		patch["spendingLimit"] = updated.SpendingLimit
+	}
+	if updated.AllowedDaysActive != card.AllowedDaysActive || !slices.Equal(updated.AllowedDays, card.AllowedDays) {
+		patch["allowedDaysActive"] = updated.AllowedDaysActive
+		patch["allowedDays"] = append([]string{}, updated.AllowedDays...)
+	}
+	categoryIds := func(card *Card) []int64 {
+		ids := []int64{}
+		for _, category := range card.AllowedCategories {
+			ids = append(ids, category.TransactionCategoryId)
+		}
+		return ids
+	}
+	if updated.AllowedCategoriesActive != card.AllowedCategoriesActive ||
+		!slices.Equal(categoryIds(updated), categoryIds(card)) {
+		refs := []categoryRef{}
+		for _, id := range categoryIds(updated) {
+			refs = append(refs, categoryRef{id})
+		}
+		patch["allowedCategoriesActive"] = updated.AllowedCategoriesActive
+		patch["allowedCategories"] = refs
+	}
+	return patch
+}
+
+// Update applies updates to a copy of the card and saves only the fields
+// they changed, so that other fields changed on the server since card was
+// fetched are left alone:
+//
+//	card, err = card.Update(bento.SetAlias("Travel"), bento.SetLimit(1000, bento.PERIOD_MONTH))
+//
+// card should be as last returned by the API; changes made to it directly
+// are not sent. Update returns the card as the server has it after the
+// update. card itself is not modified.
+func (card *Card) Update(updates ...CardUpdate) (*Card, error) {
+	return card.UpdateContext(context.Background(), updates...)
+}
+
+// UpdateContext is like Update but uses ctx for the request.
+func (card *Card) UpdateContext(ctx context.Context, updates ...CardUpdate) (*Card, error) {
+	updated := *card
+	updated.AllowedDays = slices.Clone(card.AllowedDays)
+	updated.AllowedCategories = slices.Clone(card.AllowedCategories)
+	for _, update := range updates {
+		update(&updated)
+	}
+
+	var problems []string
+	if updated.SpendingLimit.Active && updated.SpendingLimit != card.SpendingLimit {
+		problems = append(problems, limitProblems(updated.SpendingLimit)...)
+	}
+	problems = append(problems, restrictionProblems(updated.AllowedDays, updated.AllowedCategories)...)
+	if len(problems) > 0 {
+		return nil, errors.New(fmt.Sprintf("Invalid card update: %s", strings.Join(problems, ", ")))
+	}
+
+	patch := cardPatch(card, &updated)
+	if len(patch) == 0 {
+		return card.session.GetCardContext(ctx, card.CardId)
+	}
+	bs, err := card.session.call(ctx, "PUT", fmt.Sprintf("/cards/%d", card.CardId), patch)
+	if err != nil {
+		return nil, err
+	}
+
+	var cardResp Card
+	err = json.Unmarshal(bs, &cardResp)
+	if err != nil {
+		return nil, err
+	}
+
+	cardResp.session = card.session
+	return &cardResp, nil
+}
@@ -0,0 +1,260 @@
+package bento
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewCardRequestValidate(t *testing.T) {
+	t.Log("TestNewCardRequestValidate")
+	valid := []NewCardRequest{
+		{Type: EMPLOYEE_CARD},
+		{Type: CATEGORY_CARD, TransactionCategoryId: 3},
+		{Type: BUSINESS_OWNER_CARD, SpendingLimit: &SpendingLimit{Active: true, Amount: 100, Period: PERIOD_WEEK}},
+		{Type: EMPLOYEE_CARD, SpendingLimit: &SpendingLimit{Amount: 100, Period: PERIOD_CUSTOM, CustomStartDate: 1, CustomEndDate: 2}},
+		{Type: EMPLOYEE_CARD, AllowedDays: []string{"MONDAY", "FRIDAY"}, AllowedCategories: []Category{{TransactionCategoryId: 1}}},
+	}
+	for _, request := range valid {
+		if err := request.Validate(); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", request, err)
+		}
+	}
+
+	invalid := []NewCardRequest{
+		{},
+		{Type: "DebitCard"},
+		{Type: CATEGORY_CARD},
+		{Type: EMPLOYEE_CARD, TransactionCategoryId: 3},
+		{Type: EMPLOYEE_CARD, SpendingLimit: &SpendingLimit{Amount: 0, Period: PERIOD_DAY}},
+		{Type: EMPLOYEE_CARD, SpendingLimit: &SpendingLimit{Amount: 100, Period: "Fortnight"}},
+		{Type: EMPLOYEE_CARD, SpendingLimit: &SpendingLimit{Amount: 100, Period: PERIOD_CUSTOM}},
+		{Type: EMPLOYEE_CARD, SpendingLimit: &SpendingLimit{Amount: 100, Period: PERIOD_CUSTOM, CustomStartDate: 2, CustomEndDate: 1}},
+		{Type: EMPLOYEE_CARD, SpendingLimit: &SpendingLimit{Amount: 100, Period: PERIOD_DAY, CustomStartDate: 1}},
+		{Type: EMPLOYEE_CARD, AllowedDays: []string{"Monday"}},
+		{Type: EMPLOYEE_CARD, AllowedCategories: []Category{{Name: "Hotels"}}},
+	}
+	for _, request := range invalid {
+		if err := request.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", request)
+		}
+	}
+}
+
+func TestNewCardOptions(t *testing.T) {
+	t.Log("TestNewCardOptions")
+	tbs := &TestSession{}
+	session := &Session{requester: testRequest(tbs)}
+
+	_, err := session.NewCard(CATEGORY_CARD, "Meals",
+		Virtual(),
+		ForUser(77),
+		InCategory(3),
+		LimitSpending(500, PERIOD_MONTH),
+		AllowDays("MONDAY", "TUESDAY"),
+		AllowCategories(Category{TransactionCategoryId: 3, Name: "Restaurants"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tbs.method != "POST" || tbs.endpoint != "/cards" {
+		t.Errorf("Unexpected request: %s %s", tbs.method, tbs.endpoint)
+	}
+
+	bs, _ := json.Marshal(tbs.args)
+	expected := `{"type":"CategoryCard","alias":"Meals","virtualCard":true,"user":{"userId":77},` +
+		`"spendingLimit":{"active":true,"amount":500,"period":"Month"},` +
+		`"allowedDaysActive":true,"allowedDays":["MONDAY","TUESDAY"],` +
+		`"allowedCategoriesActive":true,"allowedCategories":[{"transactionCategoryId":3}],` +
+		`"transactionCategoryId":3}`
+	if string(bs) != expected {
+		t.Errorf("Unexpected request body:\n%s\nexpected:\n%s", bs, expected)
+	}
+}
+
+func TestNewCardDefaults(t *testing.T) {
+	t.Log("TestNewCardDefaults")
+	tbs := &TestSession{}
+	session := &Session{requester: testRequest(tbs)}
+
+	_, err := session.NewCard(EMPLOYEE_CARD, "Testing Card")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, _ := json.Marshal(tbs.args)
+	if strings.Contains(string(bs), "lastFour") {
+		t.Errorf("Expected lastFour not to be sent, got %s", bs)
+	}
+	if string(bs) != `{"type":"EmployeeCard","alias":"Testing Card","virtualCard":false,"allowedDaysActive":false,"allowedCategoriesActive":false}` {
+		t.Errorf("Unexpected request body: %s", bs)
+	}
+}
+
+func TestCreateCardInvalidNotSent(t *testing.T) {
+	t.Log("TestCreateCardInvalidNotSent")
+	tbs := &TestSession{}
+	session := &Session{requester: testRequest(tbs)}
+
+	_, err := session.CreateCard(NewCardRequest{Type: CATEGORY_CARD, Alias: "Meals"})
+	if err == nil || !strings.Contains(err.Error(), "TransactionCategoryId") {
+		t.Errorf("Expected a missing category to be reported, got %v", err)
+	}
+	if tbs.endpoint != "" {
+		t.Errorf("Expected no request, got %s %s", tbs.method, tbs.endpoint)
+	}
+}
+
+func TestCardUpdate(t *testing.T) {
+	t.Log("TestCardUpdate")
+	tbs := &TestSession{}
+	session := &Session{requester: testRequest(tbs)}
+	card, err := session.GetCard(12345)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := card.Update(SetAlias("Travel"), SetLimit(1000, PERIOD_MONTH), SetAllowedDays())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tbs.method != "PUT" || tbs.endpoint != "/cards/12345" {
+		t.Errorf("Unexpected request: %s %s", tbs.method, tbs.endpoint)
+	}
+	bs, _ := json.Marshal(tbs.args)
+	expected := `{"alias":"Travel","allowedDays":[],"allowedDaysActive":false,` +
+		`"spendingLimit":{"active":true,"amount":1000,"period":"Month"}}`
+	if string(bs) != expected {
+		t.Errorf("Unexpected request body:\n%s\nexpected:\n%s", bs, expected)
+	}
+	if card.Alias != "My Card" || len(card.AllowedDays) != 1 {
+		t.Errorf("Expected the card not to be modified, got %+v", card)
+	}
+	if updated.session != session {
+		t.Error(`Expected the updated card to be bound to the session`)
+	}
+
+	// Setting a field to its current value sends nothing, and just
+	// refreshes the card.
+	_, err = card.Update(SetAlias("My Card"), SetAllowedCategories(Category{TransactionCategoryId: 10, Name: "Renamed"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tbs.method != "GET" || tbs.endpoint != "/cards/12345" {
+		t.Errorf("Expected only a refresh, got %s %s %v", tbs.method, tbs.endpoint, tbs.args)
+	}
+
+	_, err = card.Update(ClearLimit(), SetAllowedCategories())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, _ = json.Marshal(tbs.args)
+	expected = `{"allowedCategories":[],"allowedCategoriesActive":false,"spendingLimit":{"active":false}}`
+	if string(bs) != expected {
+		t.Errorf("Unexpected request body:\n%s\nexpected:\n%s", bs, expected)
+	}
+}
+
+func TestCardUpdateInvalidNotSent(t *testing.T) {
+	t.Log("TestCardUpdateInvalidNotSent")
+	tbs := &TestSession{}
+	session := &Session{requester: testRequest(tbs)}
+	card := &Card{CardId: 12345, session: session}
+
+	for _, update := range []CardUpdate{
+		SetLimit(-5, PERIOD_DAY),
+		SetLimit(5, "Fortnight"),
+		SetAllowedDays("Funday"),
+		SetAllowedCategories(Category{Name: "Hotels"}),
+	} {
+		if _, err := card.Update(update); err == nil {
+			t.Error("Expected an invalid update to be rejected")
+		}
+	}
+	if tbs.endpoint != "" {
+		t.Errorf("Expected no request, got %s %s", tbs.method, tbs.endpoint)
+	}
+}
+
+// statusRequest serves card 12345 with the status and last four digits it
+// was last saved or activated with.
+func statusRequest(tbs *TestSession) requestFunc {
+	return func(ctx context.Context, session *Session, method, endpoint string, args interface{}) ([]byte, error) {
+		tbs.method = method
+		tbs.endpoint = endpoint
+		tbs.args = args
+		card := *args.(*Card)
+		card.UpdatedOn++
+		return json.Marshal(&card)
+	}
+}
+
+func TestCardOperationsFailureLeavesCard(t *testing.T) {
+	t.Log("TestCardOperationsFailureLeavesCard")
+	for _, session := range []*Session{
+		{requester: testRequestFailures(nil)},
+		{requester: testRequestFailures(nil), refreshInPlace: true},
+	} {
+		card := &Card{CardId: 12345, Status: STATUS_TURNED_OFF, session: session}
+		before := *card
+
+		if _, err := card.Activate("1234"); err == nil {
+			t.Error(`Expected Activate to fail`)
+		}
+		if _, err := card.TurnOn(); err == nil {
+			t.Error(`Expected TurnOn to fail`)
+		}
+		if card.Status != before.Status || card.LastFour != before.LastFour {
+			t.Errorf("Expected the card not to be modified, got %+v", card)
+		}
+	}
+
+	// The request succeeds, but the card comes back in the wrong state.
+	session := &Session{requester: testRequest(nil), refreshInPlace: true}
+	card := &Card{CardId: 12345, Status: STATUS_TURNED_ON, Alias: "Mine", session: session}
+	if _, err := card.TurnOff(); err == nil {
+		t.Error(`Expected TurnOff to fail when the card stays on`)
+	}
+	if card.Status != STATUS_TURNED_ON || card.Alias != "Mine" {
+		t.Errorf("Expected the card not to be modified, got %+v", card)
+	}
+}
+
+func TestCardOperationsReturnNewState(t *testing.T) {
+	t.Log("TestCardOperationsReturnNewState")
+	tbs := &TestSession{}
+	session := &Session{requester: statusRequest(tbs)}
+	card := &Card{CardId: 12345, Status: STATUS_TURNED_OFF, session: session}
+
+	activated, err := card.Activate("1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tbs.endpoint != "/cards/12345/activation" || activated.LastFour != "1234" {
+		t.Errorf("Unexpected activation: %s %+v", tbs.endpoint, activated)
+	}
+	on, err := card.TurnOn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if on.Status != STATUS_TURNED_ON || on.session != session {
+		t.Errorf("Expected the card to be turned on, got %+v", on)
+	}
+	if card.Status != STATUS_TURNED_OFF || card.LastFour != "" || card.UpdatedOn != 0 {
+		t.Errorf("Expected the card not to be modified, got %+v", card)
+	}
+
+	session.refreshInPlace = true
+	on, err = card.TurnOn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.Status != STATUS_TURNED_ON || card.UpdatedOn != 1 {
+		t.Errorf("Expected the card to be refreshed, got %+v", card)
+	}
+	if on == card {
+		t.Error(`Expected a new card to be returned`)
+	}
+	if _, err := card.TurnOff(); err != nil || card.Status != STATUS_TURNED_OFF || card.UpdatedOn != 2 {
+		t.Errorf("Expected the card to be turned off in place, got %+v, %v", card, err)
+	}
+}
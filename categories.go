@@ -0,0 +1,136 @@
+package bento
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Categories is the catalog of transaction categories, as returned by
+// GetCategories.
+type Categories []Category
+
+// ById returns the category with the given TransactionCategoryId.
+func (categories Categories) ById(categoryId int64) (Category, bool) {
+	for _, category := range categories {
+		if category.TransactionCategoryId == categoryId {
+			return category, true
+		}
+	}
+	return Category{}, false
+}
+
+// ByName returns the category with the given name, ignoring case and
+// surrounding space.
+func (categories Categories) ByName(name string) (Category, bool) {
+	name = strings.TrimSpace(name)
+	for _, category := range categories {
+		if strings.EqualFold(category.Name, name) {
+			return category, true
+		}
+	}
+	return Category{}, false
+}
+
+// ByMcc returns the category that includes the given merchant category
+// code.
+func (categories Categories) ByMcc(mcc int64) (Category, bool) {
+	for _, category := range categories {
+		if slices.Contains(category.Mccs, mcc) {
+			return category, true
+		}
+	}
+	return Category{}, false
+}
+
+// Named returns the categories with the given names, as ByName finds them,
+// for use in Card.AllowedCategories. It fails if any name is unknown.
+func (categories Categories) Named(names ...string) ([]Category, error) {
+	named := make([]Category, 0, len(names))
+	for _, name := range names {
+		category, ok := categories.ByName(name)
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("No transaction category named %q", name))
+		}
+		named = append(named, category)
+	}
+	return named, nil
+}
+
+// GetCategories returns the catalog of transaction categories. The catalog
+// is fetched once and cached on the session; use RefreshCategories to fetch
+// it again.
+func (session *Session) GetCategories() (Categories, error) {
+	return session.GetCategoriesContext(context.Background())
+}
+
+// GetCategoriesContext is like GetCategories but uses ctx for the request.
+func (session *Session) GetCategoriesContext(ctx context.Context) (Categories, error) {
+	err := session.categoriesMu.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer session.categoriesMu.unlock()
+	if session.categories == nil {
+		err := session.fetchCategories(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return session.categories.clone(), nil
+}
+
+// RefreshCategories fetches the catalog of transaction categories again,
+// replacing the session's cached copy, and returns it.
+func (session *Session) RefreshCategories() (Categories, error) {
+	return session.RefreshCategoriesContext(context.Background())
+}
+
+// RefreshCategoriesContext is like RefreshCategories but uses ctx for the
+// request.
+func (session *Session) RefreshCategoriesContext(ctx context.Context) (Categories, error) {
+	err := session.categoriesMu.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer session.categoriesMu.unlock()
+	err = session.fetchCategories(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return session.categories.clone(), nil
+}
+
+// clone returns a deep copy of the catalog, so that callers can't modify the
+// session's cached copy.
+func (categories Categories) clone() Categories {
+	cloned := slices.Clone(categories)
+	for i := range cloned {
+		cloned[i].Mccs = slices.Clone(cloned[i].Mccs)
+	}
+	return cloned
+}
+
+// fetchCategories fetches the catalog into session.categories. The caller
+// must hold categoriesMu.
+func (session *Session) fetchCategories(ctx context.Context) error {
+	bs, err := session.call(ctx, "GET", "/categories", nil)
+	if err != nil {
+		return err
+	}
+
+	var categories Categories
+	err = json.Unmarshal(bs, &categories)
+	if err != nil {
+		return err
+	}
+
+	if categories == nil {
+		categories = Categories{}
+	}
+	session.categories = categories
+	return nil
+}
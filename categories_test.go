@@ -0,0 +1,146 @@
+package bento
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var SampleCategories string = `[
+  {
+	"transactionCategoryId": 10,
+	"name": "Restaurants",
+	"group": "Meals",
+	"mccs": [5812, 5814]
+  },
+  {
+	"transactionCategoryId": 11,
+	"name": "Office Supplies",
+	"group": "Office",
+	"mccs": [5943]
+  }
+]`
+
+// categoriesRequest serves SampleCategories and counts the requests for them.
+func categoriesRequest(calls *int) requestFunc {
+	return func(ctx context.Context, session *Session, method, endpoint string, args interface{}) ([]byte, error) {
+		if method != "GET" || endpoint != "/categories" {
+			return nil, errors.New("No such testing endpoint.")
+		}
+		*calls++
+		return []byte(SampleCategories), nil
+	}
+}
+
+func TestGetCategoriesCached(t *testing.T) {
+	t.Log("TestGetCategoriesCached")
+	calls := 0
+	session := &Session{requester: categoriesRequest(&calls)}
+
+	for i := 0; i < 3; i++ {
+		categories, err := session.GetCategories()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(categories) != 2 {
+			t.Errorf("Unexpected categories: %+v", categories)
+		}
+		categories[0].Name = "Changed"
+		categories[0].Mccs[0] = 1
+	}
+	if calls != 1 {
+		t.Errorf("Expected the catalog to be fetched once, got %d", calls)
+	}
+	categories, _ := session.GetCategories()
+	if categories[0].Name != "Restaurants" || categories[0].Mccs[0] != 5812 {
+		t.Error(`Expected changes to a returned catalog not to affect the cache`)
+	}
+
+	_, err := session.RefreshCategories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("Expected RefreshCategories to fetch the catalog again, got %d", calls)
+	}
+}
+
+func TestGetCategoriesWaitRespectsContext(t *testing.T) {
+	t.Log("TestGetCategoriesWaitRespectsContext")
+	fetching := make(chan struct{})
+	release := make(chan struct{})
+	session := &Session{requester: func(ctx context.Context, session *Session, method, endpoint string, args interface{}) ([]byte, error) {
+		close(fetching)
+		<-release
+		return []byte(SampleCategories), nil
+	}}
+	defer close(release)
+
+	// One caller without a deadline gets stuck fetching the catalog.
+	go session.GetCategories()
+	<-fetching
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := session.GetCategoriesContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+	if _, err := session.RefreshCategoriesContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+}
+
+func TestGetCategoriesFailure(t *testing.T) {
+	t.Log("TestGetCategoriesFailure")
+	session := &Session{requester: testRequestFailures(nil)}
+
+	_, err := session.GetCategories()
+	if err == nil {
+		t.Error("Expected failure when calling GetCategories")
+	}
+
+	// A failure isn't cached.
+	calls := 0
+	session.requester = categoriesRequest(&calls)
+	if _, err := session.GetCategories(); err != nil || calls != 1 {
+		t.Errorf("Expected the catalog to be fetched after a failure, got %d calls: %v", calls, err)
+	}
+}
+
+func TestCategoryLookups(t *testing.T) {
+	t.Log("TestCategoryLookups")
+	calls := 0
+	session := &Session{requester: categoriesRequest(&calls)}
+	categories, err := session.GetCategories()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if category, ok := categories.ById(11); !ok || category.Name != "Office Supplies" {
+		t.Errorf("Unexpected category for ID 11: %+v", category)
+	}
+	if category, ok := categories.ByName(" restaurants "); !ok || category.TransactionCategoryId != 10 {
+		t.Errorf("Unexpected category for name restaurants: %+v", category)
+	}
+	if category, ok := categories.ByMcc(5814); !ok || category.TransactionCategoryId != 10 {
+		t.Errorf("Unexpected category for MCC 5814: %+v", category)
+	}
+	if _, ok := categories.ById(12); ok {
+		t.Error(`Expected no category with ID 12`)
+	}
+	if _, ok := categories.ByMcc(4511); ok {
+		t.Error(`Expected no category for MCC 4511`)
+	}
+
+	named, err := categories.Named("Office Supplies", "Restaurants")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(named) != 2 || named[0].TransactionCategoryId != 11 || named[1].TransactionCategoryId != 10 {
+		t.Errorf("Unexpected categories: %+v", named)
+	}
+	if _, err := categories.Named("Restaurants", "Casinos"); err == nil {
+		t.Error(`Expected an unknown category name to fail`)
+	}
+}
@@ -0,0 +1,121 @@
+package bento
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// newConcurrentServer returns a server, safe for concurrent requests, that
+// serves cards and can invalidate its token with expire.
+func newConcurrentServer() (server *httptest.Server, logins func() int, expire func()) {
+	var mu sync.Mutex
+	count := 0
+	valid := ""
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/sessions" {
+			count++
+			valid = fmt.Sprintf("token-%d", count)
+			w.Header().Set("Authorization", valid)
+			w.Write([]byte(`{"apiApplicationId": 1}`))
+			return
+		}
+		if r.Header.Get("Authorization") != valid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == "GET" && r.URL.Path == "/cards" {
+			w.Write([]byte(fmt.Sprintf("[%s,%s]", SampleCard, SampleCard)))
+			return
+		}
+		w.Write([]byte(SampleCard))
+	}))
+	logins = func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return count
+	}
+	expire = func() {
+		mu.Lock()
+		defer mu.Unlock()
+		valid = ""
+	}
+	return server, logins, expire
+}
+
+func TestSessionConcurrentUse(t *testing.T) {
+	t.Log("TestSessionConcurrentUse")
+	server, _, _ := newConcurrentServer()
+	defer server.Close()
+
+	session, err := GetTestSession("access", "secret", WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("Failed to create session: %s", err)
+	}
+	card, err := session.GetCard(12345)
+	if err != nil {
+		t.Fatalf("Failed to get card: %s", err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			if _, err := session.GetCards(); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func(i int) {
+			defer wg.Done()
+			update := *card
+			update.Alias = fmt.Sprintf("Card %d", i)
+			if _, err := update.Put(); err != nil {
+				t.Error(err)
+			}
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				session.SetLogger(log.New(ioutil.Discard, "", 0))
+			} else {
+				session.ClearLogger()
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestSessionConcurrentReauth(t *testing.T) {
+	t.Log("TestSessionConcurrentReauth")
+	server, logins, expire := newConcurrentServer()
+	defer server.Close()
+
+	session, err := GetTestSession("access", "secret", WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatalf("Failed to create session: %s", err)
+	}
+
+	expire()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := session.GetCards(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := logins(); n != 2 {
+		t.Errorf("Expected concurrent requests to share a single re-login, got %d logins", n)
+	}
+}
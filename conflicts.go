@@ -0,0 +1,102 @@
+package bento
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// maxMergeAttempts is the number of times PutWithMerge tries to save a card
+// before giving up and returning the last ConflictError.
+const maxMergeAttempts = 5
+
+// WithConflictDetection makes Card.Put check that the card has not been
+// changed on the server since it was fetched, by comparing UpdatedOn, and
+// return a ConflictError instead of overwriting the change.
+//
+// The card is fetched again just before it is saved, so a change made
+// between the two requests is still overwritten. The check catches edits
+// made while the caller was working on the card, such as changes in the web
+// UI, not simultaneous ones.
+func WithConflictDetection() Option {
+	return func(session *Session) {
+		session.detectConflicts = true
+	}
+}
+
+// ConflictError is returned by Card.Put when conflict detection is on and
+// the card was changed on the server after it was fetched.
+type ConflictError struct {
+	// Mine is the card that was being saved.
+	Mine *Card
+	// Theirs is the card as it is now on the server.
+	Theirs *Card
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("Bento Error: card %d was updated on the server at %d, after the version being saved (%d)",
+		e.Mine.CardId, e.Theirs.UpdatedOn, e.Mine.UpdatedOn)
+}
+
+// IsConflict reports whether err is a ConflictError.
+func IsConflict(err error) bool {
+	var conflict *ConflictError
+	return errors.As(err, &conflict)
+}
+
+// putChecked saves the card if its UpdatedOn matches the server's.
+func (card *Card) putChecked(ctx context.Context) (*Card, error) {
+	current, err := card.session.GetCardContext(ctx, card.CardId)
+	if err != nil {
+		return nil, err
+	}
+	if current.UpdatedOn != card.UpdatedOn {
+		return nil, &ConflictError{Mine: card, Theirs: current}
+	}
+	return card.put(ctx)
+}
+
+// MergeFunc resolves a conflict found by PutWithMerge. It is given the card
+// being saved and the card as it is on the server, and returns the card to
+// save instead, or an error to give up. Returning a nil card is an error.
+type MergeFunc func(mine, theirs *Card) (*Card, error)
+
+// PutWithMerge saves the card like Put with conflict detection on, whether
+// or not the session has it turned on. If the card was changed on the
+// server, merge is called to combine the changes and the result is saved in
+// turn, until it saves or merge returns an error. After several conflicts
+// in a row, the last ConflictError is returned.
+//
+//	card, err = card.PutWithMerge(func(mine, theirs *bento.Card) (*bento.Card, error) {
+//		merged := *theirs
+//		merged.Alias = mine.Alias
+//		return &merged, nil
+//	})
+func (card *Card) PutWithMerge(merge MergeFunc) (*Card, error) {
+	return card.PutWithMergeContext(context.Background(), merge)
+}
+
+// PutWithMergeContext is like PutWithMerge but uses ctx for the requests.
+func (card *Card) PutWithMergeContext(ctx context.Context, merge MergeFunc) (*Card, error) {
+	mine := card
+	for attempt := 1; ; attempt++ {
+		saved, err := mine.putChecked(ctx)
+		var conflict *ConflictError
+		if !errors.As(err, &conflict) || attempt == maxMergeAttempts {
+			return saved, err
+		}
+
+		merged, err := merge(conflict.Mine, conflict.Theirs)
+		if err != nil {
+			return nil, err
+		}
+		if merged == nil {
+			return nil, errors.New(fmt.Sprintf("Merge of card %d returned no card", card.CardId))
+		}
+		next := *merged
+		next.CardId = card.CardId
+		next.UpdatedOn = conflict.Theirs.UpdatedOn
+		next.session = card.session
+		mine = &next
+	}
+}
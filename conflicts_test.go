@@ -0,0 +1,137 @@
+package bento
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// conflictRequest serves a single card that gets a new UpdatedOn every time
+// it is saved, and counts the PUT requests.
+func conflictRequest(stored *Card, puts *int) requestFunc {
+	return func(ctx context.Context, session *Session, method, endpoint string, args interface{}) ([]byte, error) {
+		if endpoint != "/cards/12345" {
+			return nil, errors.New("No such testing endpoint.")
+		}
+		if method == "PUT" {
+			*puts++
+			bs, _ := json.Marshal(args)
+			var card Card
+			json.Unmarshal(bs, &card)
+			card.UpdatedOn = stored.UpdatedOn + 1
+			*stored = card
+		}
+		return json.Marshal(stored)
+	}
+}
+
+func TestPutConflict(t *testing.T) {
+	t.Log("TestPutConflict")
+	stored := &Card{CardId: 12345, Alias: "Theirs", UpdatedOn: 2}
+	puts := 0
+	session := &Session{requester: conflictRequest(stored, &puts), detectConflicts: true}
+
+	mine := &Card{CardId: 12345, Alias: "Mine", UpdatedOn: 1, session: session}
+	_, err := mine.Put()
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || !IsConflict(err) {
+		t.Fatalf("Expected a ConflictError, got %v", err)
+	}
+	if conflict.Mine != mine || conflict.Theirs.Alias != "Theirs" || conflict.Theirs.UpdatedOn != 2 {
+		t.Errorf("Unexpected versions in conflict: %+v, %+v", conflict.Mine, conflict.Theirs)
+	}
+	if puts != 0 || stored.Alias != "Theirs" {
+		t.Error(`Expected the card not to be saved`)
+	}
+
+	mine.UpdatedOn = 2
+	saved, err := mine.Put()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if puts != 1 || saved.Alias != "Mine" || saved.UpdatedOn != 3 {
+		t.Errorf("Expected the card to be saved, got %+v", saved)
+	}
+}
+
+func TestPutWithoutConflictDetection(t *testing.T) {
+	t.Log("TestPutWithoutConflictDetection")
+	stored := &Card{CardId: 12345, Alias: "Theirs", UpdatedOn: 2}
+	puts := 0
+	session := &Session{requester: conflictRequest(stored, &puts)}
+
+	mine := &Card{CardId: 12345, Alias: "Mine", UpdatedOn: 1, session: session}
+	if _, err := mine.Put(); err != nil {
+		t.Fatal(err)
+	}
+	if puts != 1 || stored.Alias != "Mine" {
+		t.Error(`Expected Put to overwrite the card`)
+	}
+}
+
+func TestPutWithMerge(t *testing.T) {
+	t.Log("TestPutWithMerge")
+	stored := &Card{CardId: 12345, Alias: "Theirs", SpendingLimit: SpendingLimit{Active: true, Amount: 50}, UpdatedOn: 2}
+	puts := 0
+	session := &Session{requester: conflictRequest(stored, &puts)}
+
+	mine := &Card{CardId: 12345, Alias: "Mine", UpdatedOn: 1, session: session}
+	merges := 0
+	saved, err := mine.PutWithMerge(func(mine, theirs *Card) (*Card, error) {
+		merges++
+		merged := *theirs
+		merged.Alias = mine.Alias
+		return &merged, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merges != 1 || puts != 1 {
+		t.Errorf("Expected 1 merge and 1 PUT, got %d and %d", merges, puts)
+	}
+	if saved.Alias != "Mine" || saved.SpendingLimit.Amount != 50 {
+		t.Errorf("Expected both changes to be saved, got %+v", saved)
+	}
+	if mine.UpdatedOn != 1 || mine.SpendingLimit.Active {
+		t.Error(`Expected the card not to be modified`)
+	}
+
+	mergeErr := errors.New("Can't merge")
+	mine = &Card{CardId: 12345, Alias: "Mine", UpdatedOn: 1, session: session}
+	_, err = mine.PutWithMerge(func(mine, theirs *Card) (*Card, error) {
+		return nil, mergeErr
+	})
+	if err != mergeErr || puts != 1 {
+		t.Errorf("Expected the merge error and no PUT, got %v", err)
+	}
+
+	_, err = mine.PutWithMerge(func(mine, theirs *Card) (*Card, error) {
+		return nil, nil
+	})
+	if err == nil || IsConflict(err) || puts != 1 {
+		t.Errorf("Expected an error for a nil merge result and no PUT, got %v", err)
+	}
+}
+
+func TestPutWithMergeGivesUp(t *testing.T) {
+	t.Log("TestPutWithMergeGivesUp")
+	stored := &Card{CardId: 12345, UpdatedOn: 2}
+	puts := 0
+	session := &Session{requester: conflictRequest(stored, &puts)}
+
+	// Someone else saves the card every time we merge.
+	mine := &Card{CardId: 12345, UpdatedOn: 1, session: session}
+	merges := 0
+	_, err := mine.PutWithMerge(func(mine, theirs *Card) (*Card, error) {
+		merges++
+		stored.UpdatedOn++
+		return theirs, nil
+	})
+	if !IsConflict(err) {
+		t.Errorf("Expected a ConflictError, got %v", err)
+	}
+	if merges != maxMergeAttempts-1 || puts != 0 {
+		t.Errorf("Expected %d merges and no PUT, got %d and %d", maxMergeAttempts-1, merges, puts)
+	}
+}
@@ -0,0 +1,171 @@
+package bento
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// requestIdHeaders are the response headers checked, in order, for an ID
+// identifying the request in the API's own logs.
+var requestIdHeaders = []string{
+	"X-Request-Id",
+	"X-Amzn-Requestid",
+	"X-Correlation-Id",
+}
+
+// APIError is returned when the API responds with an error status or an
+// error object. Use errors.As to get at it:
+//
+//	var apiErr *bento.APIError
+//	if errors.As(err, &apiErr) {
+//		log.Printf("%s %s failed with %d", apiErr.Method, apiErr.Endpoint, apiErr.StatusCode)
+//	}
+//
+// The error object returned by the API, if any, is available as a BentoError
+// through errors.As as well.
+type APIError struct {
+	StatusCode int
+	Method     string
+	Endpoint   string
+	Header     http.Header
+	// RequestID is the ID the API assigned to the request, if it returned
+	// one.
+	RequestID string
+	// Body is the raw response body.
+	Body []byte
+	// Bento is the error object parsed from Body. It is empty if the body
+	// did not contain one, e.g. for an HTML error page.
+	Bento BentoError
+	// ApplicationId and Application identify the API application the
+	// session was logged in as, to tell apart errors from sessions using
+	// different keys.
+	ApplicationId int64
+	Application   string
+}
+
+func newAPIError(method, endpoint string, resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+		Method:     method,
+		Endpoint:   endpoint,
+		Header:     resp.Header,
+		Body:       body,
+	}
+	for _, header := range requestIdHeaders {
+		if id := resp.Header.Get(header); id != "" {
+			apiErr.RequestID = id
+			break
+		}
+	}
+	if bentoErr, ok := checkError(body).(BentoError); ok {
+		apiErr.Bento = bentoErr
+	}
+	return apiErr
+}
+
+// newAPIError is like the newAPIError function, but records the session's
+// application in the error.
+func (session *Session) newAPIError(method, endpoint string, resp *http.Response, body []byte) *APIError {
+	apiErr := newAPIError(method, endpoint, resp, body)
+	app := session.Application()
+	apiErr.ApplicationId = app.ApiApplicationId
+	apiErr.Application = app.Name
+	return apiErr
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("Bento Error: %s %s returned %d %s",
+		e.Method, e.Endpoint, e.StatusCode, http.StatusText(e.StatusCode))
+	// Errors are often logged as is, so anything taken from the response
+	// is redacted.
+	if e.Bento != (BentoError{}) {
+		msg += fmt.Sprintf(": [%s], [%s]", Redact([]byte(e.Bento.Message)), Redact([]byte(e.Bento.BentoError)))
+	} else if len(e.Body) > 0 {
+		msg += fmt.Sprintf(": [%s]", truncate(Redact(e.Body), 200))
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" [request id: %s]", e.RequestID)
+	}
+	if e.ApplicationId != 0 {
+		msg += fmt.Sprintf(" [application: %s (%d)]", e.Application, e.ApplicationId)
+	}
+	return msg
+}
+
+// Unwrap returns the BentoError parsed from the response, if there is one.
+func (e *APIError) Unwrap() error {
+	if e.Bento == (BentoError{}) {
+		return nil
+	}
+	return e.Bento
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+func hasStatus(err error, statuses ...int) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, status := range statuses {
+		if apiErr.StatusCode == status {
+			return true
+		}
+	}
+	return false
+}
+
+// IsNotFound reports whether err is an APIError for a 404 Not Found response.
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is an APIError for a 401 Unauthorized
+// response, i.e. the keys or token were rejected.
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+// IsForbidden reports whether err is an APIError for a 403 Forbidden
+// response.
+func IsForbidden(err error) bool {
+	return hasStatus(err, http.StatusForbidden)
+}
+
+// IsRateLimited reports whether err is an APIError for a 429 Too Many
+// Requests response.
+func IsRateLimited(err error) bool {
+	return hasStatus(err, http.StatusTooManyRequests)
+}
+
+// IsValidation reports whether err is an APIError for a request the API
+// rejected as invalid, i.e. a 400 Bad Request or 422 Unprocessable Entity
+// response.
+func IsValidation(err error) bool {
+	return hasStatus(err, http.StatusBadRequest, http.StatusUnprocessableEntity)
+}
+
+// IsRetryable reports whether the request that returned err might succeed
+// if sent again: a 429 or 5xx response, or a network error. Context
+// cancellation and deadlines are not retryable.
+func IsRetryable(err error) bool {
+	if err == nil ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return retryableStatus(apiErr.StatusCode)
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,134 @@
+package bento
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIErrorNotFound(t *testing.T) {
+	t.Log("TestAPIErrorNotFound")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Request-Id", "abc-123")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "Card not found", "error": "NOT_FOUND"}`))
+	}))
+	defer server.Close()
+
+	session := newRetrySession(server.URL, NoRetries)
+	_, err := session.GetCard(1)
+	if !IsNotFound(err) {
+		t.Fatalf("Expected IsNotFound, got: %v", err)
+	}
+	if IsUnauthorized(err) || IsRateLimited(err) || IsRetryable(err) {
+		t.Errorf("Expected a 404 to match only IsNotFound: %v", err)
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an *APIError, got: %T", err)
+	}
+	if apiErr.Method != "GET" || apiErr.Endpoint != "/cards/1" {
+		t.Errorf("Expected GET /cards/1, got %s %s", apiErr.Method, apiErr.Endpoint)
+	}
+	if apiErr.RequestID != "abc-123" {
+		t.Errorf(`Expected RequestID == "abc-123", got %q`, apiErr.RequestID)
+	}
+
+	var bentoErr BentoError
+	if !errors.As(err, &bentoErr) {
+		t.Fatal("Expected the BentoError to be available through errors.As")
+	}
+	if bentoErr.Message != "Card not found" || bentoErr.BentoError != "NOT_FOUND" {
+		t.Errorf("Unexpected BentoError: %+v", bentoErr)
+	}
+}
+
+func TestAPIErrorHTMLBody(t *testing.T) {
+	t.Log("TestAPIErrorHTMLBody")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("<html>500 Error</html>"))
+	}))
+	defer server.Close()
+
+	session := newRetrySession(server.URL, NoRetries)
+	_, err := session.GetBusiness()
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Expected an *APIError, got: %v", err)
+	}
+	if apiErr.StatusCode != 500 || string(apiErr.Body) != "<html>500 Error</html>" {
+		t.Errorf("Unexpected APIError: %+v", apiErr)
+	}
+	if !IsRetryable(err) {
+		t.Error("Expected a 500 to be retryable")
+	}
+	if errors.As(err, &BentoError{}) {
+		t.Error("Expected no BentoError for an HTML body")
+	}
+}
+
+func TestAPIErrorInSuccessfulResponse(t *testing.T) {
+	t.Log("TestAPIErrorInSuccessfulResponse")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message": "Invalid alias", "error": "VALIDATION"}`))
+	}))
+	defer server.Close()
+
+	session := newRetrySession(server.URL, NoRetries)
+	_, err := session.NewCard(EMPLOYEE_CARD, "")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 200 {
+		t.Fatalf("Expected an *APIError with status 200, got: %v", err)
+	}
+	if apiErr.Bento.Message != "Invalid alias" {
+		t.Errorf(`Expected Bento.Message == "Invalid alias", got %q`, apiErr.Bento.Message)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	t.Log("TestIsRetryable")
+	cases := []struct {
+		err       error
+		retryable bool
+	}{
+		{nil, false},
+		{errors.New("boom"), false},
+		{context.Canceled, false},
+		{&APIError{StatusCode: 429}, true},
+		{&APIError{StatusCode: 503}, true},
+		{&APIError{StatusCode: 400}, false},
+		{&APIError{StatusCode: 401}, false},
+	}
+	for _, c := range cases {
+		if IsRetryable(c.err) != c.retryable {
+			t.Errorf("IsRetryable(%v): expected %t", c.err, c.retryable)
+		}
+	}
+
+	_, err := http.Get("http://127.0.0.1:0/")
+	if !IsRetryable(err) {
+		t.Errorf("Expected connection error to be retryable: %v", err)
+	}
+	if !IsValidation(&APIError{StatusCode: 422}) {
+		t.Error("Expected a 422 to be a validation error")
+	}
+}
+
+func TestAPIErrorRedacted(t *testing.T) {
+	t.Log("TestAPIErrorRedacted")
+	withBody := &APIError{StatusCode: 500, Method: "GET", Endpoint: "/cards/1/pan",
+		Body: []byte(`{"pan":"4111111111111111","cvv":"123"`)}
+	withBento := &APIError{StatusCode: 400, Method: "PUT", Endpoint: "/cards/1",
+		Bento: BentoError{Message: "Card 4111111111111111 rejected", BentoError: "INVALID"}}
+	for _, err := range []*APIError{withBody, withBento} {
+		msg := err.Error()
+		if strings.Contains(msg, "411111111111") || strings.Contains(msg, `"123"`) {
+			t.Errorf("Expected secrets to be redacted: %s", msg)
+		}
+	}
+}
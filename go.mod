@@ -1 +1,3 @@
 module github.com/knusbaum/bento-go
+
+go 1.23
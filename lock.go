@@ -0,0 +1,32 @@
+package bento
+
+import (
+	"context"
+	"sync"
+)
+
+// ctxMutex is a mutex that callers can stop waiting for when their context
+// is done. The zero value is unlocked.
+type ctxMutex struct {
+	once sync.Once
+	ch   chan struct{}
+}
+
+// lock waits for the mutex or for ctx to be done, whichever comes first. It
+// returns ctx.Err() if the mutex was not acquired.
+func (m *ctxMutex) lock(ctx context.Context) error {
+	m.once.Do(func() {
+		m.ch = make(chan struct{}, 1)
+	})
+	select {
+	case m.ch <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// unlock releases a mutex acquired with lock.
+func (m *ctxMutex) unlock() {
+	<-m.ch
+}
@@ -0,0 +1,206 @@
+package bento
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"log/slog"
+	"mime"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces secrets in logged request and response bodies.
+const Redacted = "REDACTED"
+
+// WithLogger sets a structured logger on the session. See
+// SetStructuredLogger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(session *Session) {
+		session.logger = logger
+	}
+}
+
+// SetStructuredLogger sets a *slog.Logger on the session. Every request is
+// logged at debug level, with its method, endpoint, attempt, status,
+// latency and body, and the ID and name of the session's API application.
+// Retries and error responses are logged at warn level.
+//
+// Bodies are always passed through Redact first, so card numbers, CVVs,
+// secret keys and authorization tokens never reach the logger.
+func (session *Session) SetStructuredLogger(logger *slog.Logger) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.logger = logger
+}
+
+// newLogLogger adapts a *log.Logger to slog, printing every record at every
+// level as a single line through it.
+func newLogLogger(logger *log.Logger) *slog.Logger {
+	return slog.New(slog.NewTextHandler(logWriter{logger}, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			// The *log.Logger adds its own timestamp if it wants one.
+			if attr.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	}))
+}
+
+type logWriter struct {
+	logger *log.Logger
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	w.logger.Print(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
+
+// slogger returns the session's logger, or nil if it has none.
+func (session *Session) slogger() *slog.Logger {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+	return session.logger
+}
+
+// log logs msg with attrs if the session has a logger. Once the session has
+// logged in, the application's ID and name are added to attrs.
+func (session *Session) log(ctx context.Context, level slog.Level, msg string, attrs ...slog.Attr) {
+	logger := session.slogger()
+	if logger == nil || !logger.Enabled(ctx, level) {
+		return
+	}
+	if app := session.Application(); app.ApiApplicationId != 0 {
+		attrs = append(attrs,
+			slog.Int64("application_id", app.ApiApplicationId),
+			slog.String("application", app.Name))
+	}
+	logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// loggedBody returns body as it is logged: redacted if it is JSON or text,
+// and otherwise only described, since receipts can be large binary files.
+func loggedBody(contentType string, body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if contentType == "" || err != nil || mediaType == "application/json" || strings.HasPrefix(mediaType, "text/") {
+		return Redact(body)
+	}
+	return fmt.Sprintf("[%d bytes of %s]", len(body), mediaType)
+}
+
+// redactedKeys are the JSON object keys whose values Redact replaces.
+var redactedKeys = map[string]bool{
+	"pan":           true,
+	"cvv":           true,
+	"secretkey":     true,
+	"authorization": true,
+}
+
+// panPattern matches anything that looks like a card number.
+var panPattern = regexp.MustCompile(`\b\d{13,19}\b`)
+
+// redactedFieldPattern matches a redacted key and its value in a body that
+// isn't valid JSON, such as a truncated response. The value may be an
+// unterminated string.
+var redactedFieldPattern = regexp.MustCompile(`(?i)"(pan|cvv|secretKey|authorization)"\s*:\s*("[^"]*"?|[^,}\s]*)`)
+
+// Redact returns body with secrets removed. In a JSON body the values of pan,
+// cvv, secretKey and authorization fields are replaced with Redacted, except
+// that the last four digits of a card number are kept, and the body is
+// compacted. In a body that isn't valid JSON, such as a truncated response,
+// the values of those fields are all replaced with Redacted. In any body,
+// strings of 13 to 19 digits that pass the Luhn check are masked as card
+// numbers.
+func Redact(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var v interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if decoder.Decode(&v) == nil && !decoder.More() {
+		if bs, err := json.Marshal(redactValue("", v)); err == nil {
+			return string(bs)
+		}
+	}
+	redacted := redactedFieldPattern.ReplaceAllString(string(body), `"$1":"`+Redacted+`"`)
+	return maskPans(redacted)
+}
+
+func redactValue(key string, v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, field := range value {
+			value[k] = redactValue(k, field)
+		}
+		return value
+	case []interface{}:
+		for i, elem := range value {
+			value[i] = redactValue(key, elem)
+		}
+		return value
+	case string:
+		lower := strings.ToLower(key)
+		if lower == "pan" {
+			return maskPan(value)
+		}
+		if redactedKeys[lower] {
+			return Redacted
+		}
+		return maskPans(value)
+	case json.Number:
+		// Numbers are only redacted by key, since millisecond timestamps
+		// look a lot like card numbers.
+		lower := strings.ToLower(key)
+		if lower == "pan" {
+			return maskPan(value.String())
+		}
+		if redactedKeys[lower] {
+			return Redacted
+		}
+	}
+	return v
+}
+
+// maskPans masks everything in s that looks like a card number: a run of 13
+// to 19 digits that passes the Luhn check.
+func maskPans(s string) string {
+	return panPattern.ReplaceAllStringFunc(s, func(digits string) string {
+		if !luhn(digits) {
+			return digits
+		}
+		return maskPan(digits)
+	})
+}
+
+func luhn(digits string) bool {
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// maskPan replaces all but the last four digits of a card number.
+func maskPan(pan string) string {
+	if len(pan) <= 4 {
+		return strings.Repeat("*", len(pan))
+	}
+	return strings.Repeat("*", len(pan)-4) + pan[len(pan)-4:]
+}
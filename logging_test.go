@@ -0,0 +1,158 @@
+package bento
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	testPan    = "4111111111111111"
+	testCvv    = "987"
+	testSecret = "super-secret-key"
+	testToken  = "secret-session-token"
+)
+
+func newSecretServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sessions":
+			w.Header().Set("Authorization", testToken)
+			w.Write([]byte(`{"apiApplicationId": 1}`))
+		case "/cards/12345/pan":
+			w.Write([]byte(`{"pan": "` + testPan + `", "cvv": "` + testCvv + `"}`))
+		case "/cards/12345/billingAddress":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message": "Card ` + testPan + ` has no address", "error": "BAD"}`))
+		default:
+			w.Write([]byte(SampleCard))
+		}
+	}))
+}
+
+func assertNoSecrets(t *testing.T, logged string) {
+	t.Helper()
+	for _, secret := range []string{testPan, `"` + testCvv + `"`, "cvv=" + testCvv, testSecret, testToken} {
+		if strings.Contains(logged, secret) {
+			t.Errorf("Expected %q to be redacted from the log:\n%s", secret, logged)
+		}
+	}
+}
+
+func TestStructuredLogging(t *testing.T) {
+	t.Log("TestStructuredLogging")
+	server := newSecretServer()
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	session, err := GetTestSession("access", testSecret,
+		WithBaseURL(server.URL), WithLogger(logger), WithRetryPolicy(NoRetries))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	card, err := session.GetCard(12345)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pan, err := card.GetPanAndCvv()
+	if err != nil || pan.Pan != testPan {
+		t.Fatalf("Expected to get the PAN, got %+v, %v", pan, err)
+	}
+	if _, err := card.GetBillingAddress(); err == nil {
+		t.Fatal("Expected GetBillingAddress to fail")
+	}
+
+	assertNoSecrets(t, buf.String())
+
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Unable to parse log line %q: %s", line, err)
+		}
+		records = append(records, record)
+	}
+
+	var response map[string]interface{}
+	for _, record := range records {
+		if record["msg"] == "Received response" && record["endpoint"] == "/cards/12345/pan" {
+			response = record
+		}
+	}
+	if response == nil {
+		t.Fatalf("Expected a response record for /cards/12345/pan in:\n%s", buf.String())
+	}
+	if response["method"] != "GET" || response["status"] != float64(200) ||
+		response["attempt"] != float64(1) || response["latency"] == nil {
+		t.Errorf("Expected method, status, attempt and latency fields, got %+v", response)
+	}
+	if response["body"] != `{"cvv":"REDACTED","pan":"************1111"}` {
+		t.Errorf("Expected a redacted body, got %v", response["body"])
+	}
+
+	last := records[len(records)-1]
+	if last["level"] != "WARN" || last["status"] != float64(400) {
+		t.Errorf("Expected the error response to be logged at WARN, got %+v", last)
+	}
+}
+
+func TestSetLoggerRedacts(t *testing.T) {
+	t.Log("TestSetLoggerRedacts")
+	server := newSecretServer()
+	defer server.Close()
+
+	var buf bytes.Buffer
+	session, err := GetTestSession("access", testSecret, WithBaseURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session.SetLogger(log.New(&buf, "bento: ", 0))
+
+	card := &Card{CardId: 12345, session: session}
+	if _, err := card.GetPanAndCvv(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "bento: level=DEBUG msg=\"Sending request\" method=GET endpoint=/cards/12345/pan") {
+		t.Errorf("Expected a line per request through the *log.Logger, got:\n%s", buf.String())
+	}
+	assertNoSecrets(t, buf.String())
+
+	buf.Reset()
+	session.ClearLogger()
+	if _, err := card.GetPanAndCvv(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected nothing to be logged after ClearLogger, got:\n%s", buf.String())
+	}
+}
+
+func TestRedact(t *testing.T) {
+	t.Log("TestRedact")
+	cases := map[string]string{
+		``:                                     ``,
+		`{"accessKey": "a", "secretKey": "s"}`: `{"accessKey":"a","secretKey":"REDACTED"}`,
+		`[{"pan": 4111111111111111, "cvv": 123}]`:                  `[{"cvv":"REDACTED","pan":"************1111"}]`,
+		`{"createdOn": 1495759408000, "note": "4111111111111111"}`: `{"createdOn":1495759408000,"note":"************1111"}`,
+		`{"Authorization": "token"}`:                               `{"Authorization":"REDACTED"}`,
+		`<html>card 4111111111111111, order 1234567890123</html>`:  `<html>card ************1111, order 1234567890123</html>`,
+
+		// Truncated bodies aren't valid JSON, but are still redacted.
+		`{"pan":"4111111111111111","cvv":"123"`: `{"pan":"REDACTED","cvv":"REDACTED"`,
+		`{"secretKey":"abc"`:                    `{"secretKey":"REDACTED"`,
+		`{"pan":"40000000000`:                   `{"pan":"REDACTED"`,
+		`{"cvv": 123, "Authorization" : "tok`:   `{"cvv":"REDACTED", "Authorization":"REDACTED"`,
+	}
+	for body, expected := range cases {
+		if redacted := Redact([]byte(body)); redacted != expected {
+			t.Errorf("Redact(%s): expected %s, got %s", body, expected, redacted)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package bento
+
+import (
+	"context"
+	"net/http"
+)
+
+// Request describes an API call as it passes through middleware. Middleware
+// may modify it before passing it on.
+type Request struct {
+	Method string
+	// Endpoint is the path relative to the session's base URL, including
+	// any query string, e.g. "/cards/12345".
+	Endpoint string
+	// Body is marshaled to JSON and sent as the request body. It is nil for
+	// requests without one, and holds an already encoded form for receipt
+	// uploads.
+	Body interface{}
+	// Header holds extra headers to send with the request. It is never nil.
+	Header http.Header
+}
+
+// Handler performs an API call and returns the raw JSON response body, or
+// the file's contents for receipt downloads.
+type Handler func(ctx context.Context, req *Request) ([]byte, error)
+
+// Middleware wraps a Handler with extra behavior, such as auditing, adding
+// headers, recording metrics or serving responses from a cache. A
+// middleware may return without calling next.
+type Middleware func(next Handler) Handler
+
+// Use adds middleware to the session. Every call made through the session,
+// such as GetBusiness, GetCards or Card.Put, passes through the middleware
+// in the order it was added, so the first middleware added sees the request
+// first and the response last. Retries and re-logins happen inside the
+// chain, so middleware sees each call once.
+func (session *Session) Use(middleware ...Middleware) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.middleware = append(session.middleware[:len(session.middleware):len(session.middleware)], middleware...)
+}
+
+// WithMiddleware adds middleware to the session, as Use does.
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(session *Session) {
+		session.middleware = append(session.middleware, middleware...)
+	}
+}
+
+type headerKey struct{}
+
+// requestHeader returns the extra headers set by middleware for the request
+// made with ctx.
+func requestHeader(ctx context.Context) http.Header {
+	header, _ := ctx.Value(headerKey{}).(http.Header)
+	return header
+}
+
+// call performs an API call through the session's middleware.
+func (session *Session) call(ctx context.Context, method, endpoint string, args interface{}) ([]byte, error) {
+	session.mu.RLock()
+	middleware := session.middleware
+	session.mu.RUnlock()
+
+	handler := Handler(func(ctx context.Context, req *Request) ([]byte, error) {
+		if len(req.Header) > 0 {
+			ctx = context.WithValue(ctx, headerKey{}, req.Header)
+		}
+		return session.requester(ctx, session, req.Method, req.Endpoint, req.Body)
+	})
+	for i := len(middleware) - 1; i >= 0; i-- {
+		handler = middleware[i](handler)
+	}
+	return handler(ctx, &Request{
+		Method:   method,
+		Endpoint: endpoint,
+		Body:     args,
+		Header:   http.Header{},
+	})
+}
@@ -0,0 +1,95 @@
+package bento
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	t.Log("TestMiddlewareOrder")
+	session := &Session{requester: testRequest(nil)}
+
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(ctx context.Context, req *Request) ([]byte, error) {
+				calls = append(calls, name+" "+req.Method+" "+req.Endpoint)
+				bs, err := next(ctx, req)
+				calls = append(calls, name+" done")
+				return bs, err
+			}
+		}
+	}
+	session.Use(trace("outer"), trace("inner"))
+
+	if _, err := session.GetBusiness(); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"outer GET /businesses/me", "inner GET /businesses/me", "inner done", "outer done"}
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, calls)
+			break
+		}
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	t.Log("TestMiddlewareShortCircuit")
+	session := &Session{requester: testRequestFailures(nil)}
+
+	// A cache that answers GET /cards/12345 without calling the API.
+	session.Use(func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) ([]byte, error) {
+			if req.Method == "GET" && req.Endpoint == "/cards/12345" {
+				return []byte(SampleCard), nil
+			}
+			return next(ctx, req)
+		}
+	})
+
+	card, err := session.GetCard(12345)
+	if err != nil {
+		t.Fatalf("Expected the cached card, got: %s", err)
+	}
+	if _, err := card.Put(); err == nil {
+		t.Error("Expected Put to reach the failing requester")
+	}
+}
+
+func TestMiddlewareModifiesRequest(t *testing.T) {
+	t.Log("TestMiddlewareModifiesRequest")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Audit-User") != "ops" {
+			t.Errorf(`Expected X-Audit-User == "ops", got %q`, r.Header.Get("X-Audit-User"))
+		}
+		if r.Header.Get("Authorization") != "token" {
+			t.Errorf("Expected middleware not to override the Authorization header")
+		}
+		if r.Method != "PUT" {
+			t.Errorf("Expected PUT, got %s", r.Method)
+		}
+		w.Write([]byte(SampleCard))
+	}))
+	defer server.Close()
+
+	session := newRetrySession(server.URL, NoRetries)
+	session.authorization = "token"
+	session.Use(func(next Handler) Handler {
+		return func(ctx context.Context, req *Request) ([]byte, error) {
+			req.Header.Set("X-Audit-User", "ops")
+			req.Header.Set("Authorization", "forged")
+			return next(ctx, req)
+		}
+	})
+
+	card := &Card{CardId: 12345, session: session}
+	if _, err := card.Put(); err != nil {
+		t.Fatal(err)
+	}
+}
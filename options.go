@@ -0,0 +1,61 @@
+package bento
+
+import (
+	"net/http"
+	"strings"
+)
+
+const defaultUserAgent = "bento-go"
+
+// Option configures a Session. Options are passed to GetProductionSession,
+// GetTestSession and their Context variants, and are applied in order before
+// the session logs in.
+type Option func(*Session)
+
+// WithHTTPClient makes the session send every request, including the login
+// request, through client. The client is reused for the life of the session,
+// so its transport's connection pool is shared by all calls.
+func WithHTTPClient(client *http.Client) Option {
+	return func(session *Session) {
+		session.client = client
+	}
+}
+
+// WithTransport makes the session's client use transport to send requests.
+// This is the place to configure proxies, TLS roots and connection pooling.
+// If WithHTTPClient was applied earlier, the client is copied rather than
+// modified.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(session *Session) {
+		client := &http.Client{}
+		if session.client != nil {
+			*client = *session.client
+		}
+		client.Transport = transport
+		session.client = client
+	}
+}
+
+// WithBaseURL overrides the API root the session talks to, e.g.
+// "http://localhost:8080/api". A trailing slash is ignored.
+func WithBaseURL(uri string) Option {
+	return func(session *Session) {
+		session.apiUri = strings.TrimRight(uri, "/")
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(session *Session) {
+		session.userAgent = userAgent
+	}
+}
+
+// WithRefreshInPlace makes Card.Activate, TurnOn and TurnOff copy the card
+// they return into the card they were called on when they succeed, so the
+// caller's Card keeps matching the server. A failed call never changes it.
+func WithRefreshInPlace() Option {
+	return func(session *Session) {
+		session.refreshInPlace = true
+	}
+}
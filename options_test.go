@@ -0,0 +1,78 @@
+package bento
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type countingTransport struct {
+	count int
+	next  http.RoundTripper
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.count++
+	return t.next.RoundTrip(req)
+}
+
+func newOptionsServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "my-agent/1.0" {
+			t.Errorf(`Expected User-Agent == "my-agent/1.0", got %q`, r.Header.Get("User-Agent"))
+		}
+		switch r.URL.Path {
+		case "/api/sessions":
+			w.Header().Set("Authorization", "token")
+			w.Write([]byte(`{"apiApplicationId": 1}`))
+		case "/api/cards":
+			if r.Header.Get("Authorization") != "token" {
+				t.Errorf(`Expected Authorization == "token", got %q`, r.Header.Get("Authorization"))
+			}
+			w.Write([]byte("[" + SampleCard + "]"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestSessionOptions(t *testing.T) {
+	t.Log("TestSessionOptions")
+	server := newOptionsServer(t)
+	defer server.Close()
+
+	transport := &countingTransport{next: http.DefaultTransport}
+	session, err := GetTestSession("access", "secret",
+		WithBaseURL(server.URL+"/api/"),
+		WithTransport(transport),
+		WithUserAgent("my-agent/1.0"))
+	if err != nil {
+		t.Fatalf("Failed to create session: %s", err)
+	}
+
+	cards, err := session.GetCards()
+	if err != nil {
+		t.Fatalf("Failed to get cards: %s", err)
+	}
+	if len(cards) != 1 {
+		t.Errorf("Expected 1 card, got %d", len(cards))
+	}
+	if transport.count != 2 {
+		t.Errorf("Expected login and GetCards to share the transport, got %d round trips", transport.count)
+	}
+}
+
+func TestWithTransportCopiesClient(t *testing.T) {
+	t.Log("TestWithTransportCopiesClient")
+	client := &http.Client{}
+	session := &Session{}
+	WithHTTPClient(client)(session)
+	WithTransport(&countingTransport{})(session)
+
+	if client.Transport != nil {
+		t.Error("Expected WithTransport to leave the caller's client untouched")
+	}
+	if session.client == client || session.client.Transport == nil {
+		t.Error("Expected WithTransport to install the transport on a copy of the client")
+	}
+}
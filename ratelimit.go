@@ -0,0 +1,176 @@
+package bento
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithRateLimit limits the session to requestsPerSecond requests on average,
+// allowing bursts of up to burst requests. The limit is shared by every
+// goroutine using the session, and applies to each attempt of a retried
+// request. requestsPerSecond <= 0 means no limit.
+func WithRateLimit(requestsPerSecond float64, burst int) Option {
+	return func(session *Session) {
+		if requestsPerSecond <= 0 {
+			session.rateLimiter = nil
+			return
+		}
+		session.rateLimiter = newTokenBucket(requestsPerSecond, burst)
+	}
+}
+
+// WithMaxInFlight limits the number of requests the session has outstanding
+// at once. Callers beyond the limit wait for a request to finish. n <= 0
+// means no limit.
+func WithMaxInFlight(n int) Option {
+	return func(session *Session) {
+		if n <= 0 {
+			session.inFlight = nil
+			return
+		}
+		session.inFlight = make(chan struct{}, n)
+	}
+}
+
+// LimiterStats reports how much time callers of a session have spent waiting
+// on the limits set by WithRateLimit and WithMaxInFlight.
+type LimiterStats struct {
+	// Requests is the number of requests that passed through the limits.
+	Requests int64
+	// Delayed is the number of those requests that had to wait.
+	Delayed int64
+	// RateLimitWait is the total time spent waiting for the rate limit.
+	RateLimitWait time.Duration
+	// InFlightWait is the total time spent waiting for a free slot under
+	// the in-flight limit.
+	InFlightWait time.Duration
+}
+
+// limiterStats is the atomically updated counterpart of LimiterStats.
+type limiterStats struct {
+	requests      int64
+	delayed       int64
+	rateLimitWait int64
+	inFlightWait  int64
+}
+
+// LimiterStats returns the session's limiter metrics so far.
+func (session *Session) LimiterStats() LimiterStats {
+	return LimiterStats{
+		Requests:      atomic.LoadInt64(&session.limiterStats.requests),
+		Delayed:       atomic.LoadInt64(&session.limiterStats.delayed),
+		RateLimitWait: time.Duration(atomic.LoadInt64(&session.limiterStats.rateLimitWait)),
+		InFlightWait:  time.Duration(atomic.LoadInt64(&session.limiterStats.inFlightWait)),
+	}
+}
+
+// acquire waits until the session's limits allow another request. On
+// success the caller must call the returned release function once the
+// request is done.
+func (session *Session) acquire(ctx context.Context) (release func(), err error) {
+	stats := &session.limiterStats
+	atomic.AddInt64(&stats.requests, 1)
+	delayed := false
+
+	if session.rateLimiter != nil {
+		waited, err := session.rateLimiter.wait(ctx)
+		atomic.AddInt64(&stats.rateLimitWait, int64(waited))
+		if err != nil {
+			return nil, err
+		}
+		delayed = waited > 0
+	}
+
+	release = func() {}
+	if session.inFlight != nil {
+		start := time.Now()
+		select {
+		case session.inFlight <- struct{}{}:
+		default:
+			delayed = true
+			select {
+			case session.inFlight <- struct{}{}:
+			case <-ctx.Done():
+				atomic.AddInt64(&stats.inFlightWait, int64(time.Since(start)))
+				return nil, ctx.Err()
+			}
+			atomic.AddInt64(&stats.inFlightWait, int64(time.Since(start)))
+		}
+		release = func() { <-session.inFlight }
+	}
+
+	if delayed {
+		atomic.AddInt64(&stats.delayed, 1)
+	}
+	return release, nil
+}
+
+// tokenBucket is a token bucket rate limiter that is safe for concurrent use.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token from the bucket, going into debt if there is none,
+// and returns how long the caller has to wait before using it.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel returns a token taken by reserve that will not be used.
+func (b *tokenBucket) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// wait blocks until a token is available and returns how long it waited.
+func (b *tokenBucket) wait(ctx context.Context) (time.Duration, error) {
+	if b.rate <= 0 {
+		return 0, nil
+	}
+	delay := b.reserve()
+	if delay == 0 {
+		return 0, nil
+	}
+	start := time.Now()
+	err := sleep(ctx, delay)
+	if err != nil {
+		b.cancel()
+	}
+	return time.Since(start), err
+}
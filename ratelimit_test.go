@@ -0,0 +1,141 @@
+package bento
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Log("TestRateLimit")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(SampleCard))
+	}))
+	defer server.Close()
+
+	session := newRetrySession(server.URL, NoRetries)
+	WithRateLimit(50, 1)(session)
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := session.GetCard(12345); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The first request goes through immediately, the other 5 are spaced
+	// 20ms apart.
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("Expected requests to be spread over at least 100ms, took %s", elapsed)
+	}
+	stats := session.LimiterStats()
+	if stats.Requests != 6 || stats.Delayed != 5 {
+		t.Errorf("Expected 6 requests with 5 delayed, got %+v", stats)
+	}
+	if stats.RateLimitWait <= 0 {
+		t.Errorf("Expected RateLimitWait > 0, got %+v", stats)
+	}
+}
+
+func TestRateLimitContextCanceled(t *testing.T) {
+	t.Log("TestRateLimitContextCanceled")
+	session := &Session{}
+	WithRateLimit(0.001, 1)(session)
+
+	if _, err := session.acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := session.acquire(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected context.DeadlineExceeded, got: %v", err)
+	}
+}
+
+func TestMaxInFlight(t *testing.T) {
+	t.Log("TestMaxInFlight")
+	var mu sync.Mutex
+	current, max := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		current++
+		if current > max {
+			max = current
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		current--
+		mu.Unlock()
+		w.Write([]byte(SampleCard))
+	}))
+	defer server.Close()
+
+	session := newRetrySession(server.URL, NoRetries)
+	WithMaxInFlight(2)(session)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := session.GetCard(12345); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if max > 2 {
+		t.Errorf("Expected at most 2 requests in flight, saw %d", max)
+	}
+	if stats := session.LimiterStats(); stats.InFlightWait <= 0 {
+		t.Errorf("Expected InFlightWait > 0, got %+v", stats)
+	}
+}
+
+func TestMaxInFlightZeroIsUnlimited(t *testing.T) {
+	t.Log("TestMaxInFlightZeroIsUnlimited")
+	for _, n := range []int{0, -1} {
+		session := &Session{}
+		WithMaxInFlight(n)(session)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		for i := 0; i < 3; i++ {
+			if _, err := session.acquire(ctx); err != nil {
+				t.Errorf("Expected WithMaxInFlight(%d) not to block, got: %v", n, err)
+			}
+		}
+		cancel()
+	}
+}
+
+func TestRateLimitZeroIsUnlimited(t *testing.T) {
+	t.Log("TestRateLimitZeroIsUnlimited")
+	for _, rate := range []float64{0, -1} {
+		session := &Session{}
+		WithRateLimit(rate, 1)(session)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		for i := 0; i < 3; i++ {
+			if _, err := session.acquire(ctx); err != nil {
+				t.Errorf("Expected WithRateLimit(%v, 1) not to block, got: %v", rate, err)
+			}
+		}
+		cancel()
+		if stats := session.LimiterStats(); stats.Delayed != 0 {
+			t.Errorf("Expected no delayed requests, got %+v", stats)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package bento
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Receipt describes a receipt attached to a transaction.
+type Receipt struct {
+	ReceiptId         int64  `json:"receiptId,omitempty"`
+	CardTransactionId int64  `json:"cardTransactionId,omitempty"`
+	FileName          string `json:"fileName,omitempty"`
+	ContentType       string `json:"contentType,omitempty"`
+	Size              int64  `json:"size,omitempty"`
+	CreatedOn         int64  `json:"createdOn,omitempty"`
+}
+
+// rawBody is a request body that is sent as is, rather than marshaled to
+// JSON.
+type rawBody struct {
+	contentType string
+	data        []byte
+}
+
+type rawResponseKey struct{}
+
+// rawResponse reports whether the response to the request made with ctx
+// should be returned as is, rather than checked for JSON.
+func rawResponse(ctx context.Context) bool {
+	raw, _ := ctx.Value(rawResponseKey{}).(bool)
+	return raw
+}
+
+// receiptContentType returns the content type of a receipt from its file
+// name, or from its contents if the name doesn't tell. Only images and PDFs
+// are accepted.
+func receiptContentType(name string, data []byte) (string, error) {
+	contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(name)))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || (mediaType != "application/pdf" && !strings.HasPrefix(mediaType, "image/")) {
+		return "", errors.New(fmt.Sprintf("Receipt %s is %s, not an image or PDF", name, contentType))
+	}
+	return mediaType, nil
+}
+
+var quoteEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// receiptForm encodes a receipt as a multipart form with a single file
+// field.
+func receiptForm(name string, r io.Reader) (rawBody, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return rawBody{}, err
+	}
+	contentType, err := receiptContentType(name, data)
+	if err != nil {
+		return rawBody{}, err
+	}
+
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition",
+		fmt.Sprintf(`form-data; name="file"; filename="%s"`, quoteEscaper.Replace(filepath.Base(name))))
+	header.Set("Content-Type", contentType)
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return rawBody{}, err
+	}
+	part.Write(data)
+	err = form.Close()
+	if err != nil {
+		return rawBody{}, err
+	}
+	return rawBody{contentType: form.FormDataContentType(), data: buf.Bytes()}, nil
+}
+
+func (transaction *Transaction) receiptsEndpoint() string {
+	return fmt.Sprintf("/transactions/%d/receipts", transaction.CardTransactionId)
+}
+
+// UploadReceipt attaches the image or PDF read from r to the transaction.
+// name is the receipt's file name, whose extension gives its type. If the
+// extension is unknown, the type is detected from the contents.
+func (transaction *Transaction) UploadReceipt(name string, r io.Reader) (*Receipt, error) {
+	return transaction.UploadReceiptContext(context.Background(), name, r)
+}
+
+// UploadReceiptContext is like UploadReceipt but uses ctx for the request.
+func (transaction *Transaction) UploadReceiptContext(ctx context.Context, name string, r io.Reader) (*Receipt, error) {
+	form, err := receiptForm(name, r)
+	if err != nil {
+		return nil, err
+	}
+	bs, err := transaction.session.call(ctx, "POST", transaction.receiptsEndpoint(), form)
+	if err != nil {
+		return nil, err
+	}
+
+	var receipt Receipt
+	err = json.Unmarshal(bs, &receipt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &receipt, nil
+}
+
+// Receipts returns the receipts attached to the transaction.
+func (transaction *Transaction) Receipts() ([]Receipt, error) {
+	return transaction.ReceiptsContext(context.Background())
+}
+
+// ReceiptsContext is like Receipts but uses ctx for the request.
+func (transaction *Transaction) ReceiptsContext(ctx context.Context) ([]Receipt, error) {
+	bs, err := transaction.session.call(ctx, "GET", transaction.receiptsEndpoint(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var receipts []Receipt
+	err = json.Unmarshal(bs, &receipts)
+	if err != nil {
+		return nil, err
+	}
+
+	return receipts, nil
+}
+
+// DownloadReceipt writes the contents of one of the transaction's receipts
+// to w.
+func (transaction *Transaction) DownloadReceipt(receiptId int64, w io.Writer) error {
+	return transaction.DownloadReceiptContext(context.Background(), receiptId, w)
+}
+
+// DownloadReceiptContext is like DownloadReceipt but uses ctx for the
+// request.
+func (transaction *Transaction) DownloadReceiptContext(ctx context.Context, receiptId int64, w io.Writer) error {
+	ctx = context.WithValue(ctx, rawResponseKey{}, true)
+	endpoint := fmt.Sprintf("%s/%d", transaction.receiptsEndpoint(), receiptId)
+	bs, err := transaction.session.call(ctx, "GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(bs)
+	return err
+}
+
+// DeleteReceipt removes one of the transaction's receipts.
+func (transaction *Transaction) DeleteReceipt(receiptId int64) error {
+	return transaction.DeleteReceiptContext(context.Background(), receiptId)
+}
+
+// DeleteReceiptContext is like DeleteReceipt but uses ctx for the request.
+func (transaction *Transaction) DeleteReceiptContext(ctx context.Context, receiptId int64) error {
+	endpoint := fmt.Sprintf("%s/%d", transaction.receiptsEndpoint(), receiptId)
+	_, err := transaction.session.call(ctx, "DELETE", endpoint, nil)
+	return err
+}
+
+// ReceiptAttachment is the outcome of attaching one file with
+// AttachReceipts. Err is set if it could not be attached.
+type ReceiptAttachment struct {
+	Path              string
+	CardTransactionId int64
+	Receipt           *Receipt
+	Err               error
+}
+
+// receiptTransactionId returns the transaction ID a receipt's file name
+// refers to. The name without its extension must be the ID, or the ID
+// followed by "-" or "_" and a description that doesn't start with a digit,
+// so that names such as "20240115_103000.jpg" aren't taken for IDs.
+func receiptTransactionId(name string) (int64, bool) {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	end := 0
+	for end < len(name) && name[end] >= '0' && name[end] <= '9' {
+		end++
+	}
+	if end == 0 {
+		return 0, false
+	}
+	if rest := name[end:]; rest != "" {
+		if len(rest) < 2 || (rest[0] != '-' && rest[0] != '_') || (rest[1] >= '0' && rest[1] <= '9') {
+			return 0, false
+		}
+	}
+	id, err := strconv.ParseInt(name[:end], 10, 64)
+	return id, err == nil
+}
+
+// AttachReceipts uploads every file in dir named after a CardTransactionId
+// as a receipt for that transaction. The name may add a description after
+// "-" or "_", so "1234.pdf", "1234-lunch.jpg" and "1234_taxi.png" are all
+// attached to transaction 1234. Other files, including ones such as
+// "20240115_103000.jpg" that merely start with digits, and subdirectories
+// are skipped.
+//
+// A file that can't be attached doesn't stop the others; its error is
+// reported in its ReceiptAttachment. The returned error is only set if dir
+// can't be read or ctx is done.
+func (session *Session) AttachReceipts(dir string) ([]ReceiptAttachment, error) {
+	return session.AttachReceiptsContext(context.Background(), dir)
+}
+
+// AttachReceiptsContext is like AttachReceipts but uses ctx for the
+// requests.
+func (session *Session) AttachReceiptsContext(ctx context.Context, dir string) ([]ReceiptAttachment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachments []ReceiptAttachment
+	for _, entry := range entries {
+		id, ok := receiptTransactionId(entry.Name())
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return attachments, err
+		}
+
+		attachment := ReceiptAttachment{Path: filepath.Join(dir, entry.Name()), CardTransactionId: id}
+		attachment.Receipt, attachment.Err = session.attachReceipt(ctx, id, attachment.Path)
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+func (session *Session) attachReceipt(ctx context.Context, transactionId int64, path string) (*Receipt, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	transaction := &Transaction{CardTransactionId: transactionId, session: session}
+	return transaction.UploadReceiptContext(ctx, filepath.Base(path), f)
+}
@@ -0,0 +1,116 @@
+package bento
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReceiptTransactionId(t *testing.T) {
+	t.Log("TestReceiptTransactionId")
+	cases := map[string]int64{
+		"1234.pdf":       1234,
+		"1234-lunch.jpg": 1234,
+		"1234_taxi.png":  1234,
+		"1234":           1234,
+		"lunch-1234.jpg": 0,
+		".DS_Store":      0,
+		"notes.txt":      0,
+
+		// Names that only start with digits aren't IDs.
+		"20240115_103000.jpg": 0,
+		"1234_2.png":          0,
+		"1234lunch.pdf":       0,
+		"1234-.pdf":           0,
+		"1234 lunch.pdf":      0,
+	}
+	for name, expected := range cases {
+		id, ok := receiptTransactionId(name)
+		if ok != (expected != 0) || id != expected {
+			t.Errorf("Expected %s to give transaction %d, got %d, %t", name, expected, id, ok)
+		}
+	}
+}
+
+func TestReceiptForm(t *testing.T) {
+	t.Log("TestReceiptForm")
+	form, err := receiptForm("dir/lunch.pdf", strings.NewReader("%PDF-1.4 receipt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(form.contentType)
+	if err != nil || mediaType != "multipart/form-data" {
+		t.Fatalf("Unexpected content type %s: %v", form.contentType, err)
+	}
+	part, err := multipart.NewReader(bytes.NewReader(form.data), params["boundary"]).NextPart()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(part)
+	if part.FormName() != "file" || part.FileName() != "lunch.pdf" ||
+		part.Header.Get("Content-Type") != "application/pdf" || string(data) != "%PDF-1.4 receipt" {
+		t.Errorf("Unexpected part %s %s %v: %s", part.FormName(), part.FileName(), part.Header, data)
+	}
+
+	// Without a known extension, the type is detected.
+	png := "\x89PNG\r\n\x1a\n"
+	form, err = receiptForm("receipt", strings.NewReader(png))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(form.data, []byte("Content-Type: image/png")) {
+		t.Errorf("Expected the type to be detected as image/png:\n%s", form.data)
+	}
+
+	_, err = receiptForm("notes.txt", strings.NewReader("lunch"))
+	if err == nil {
+		t.Error(`Expected a text file to be rejected`)
+	}
+}
+
+func TestDownloadReceipt(t *testing.T) {
+	t.Log("TestDownloadReceipt")
+	contents := "%PDF-1.4 \x00\x01 not json"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/transactions/7/receipts/9" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write([]byte(contents))
+	}))
+	defer server.Close()
+
+	var logged bytes.Buffer
+	session := &Session{
+		apiUri:    server.URL,
+		requester: doRequest,
+		logger:    slog.New(slog.NewTextHandler(&logged, &slog.HandlerOptions{Level: slog.LevelDebug})),
+	}
+	tx := &Transaction{CardTransactionId: 7, session: session}
+
+	var buf bytes.Buffer
+	err := tx.DownloadReceipt(9, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != contents {
+		t.Errorf("Unexpected receipt contents: %q", buf.String())
+	}
+	if strings.Contains(logged.String(), "not json") ||
+		!strings.Contains(logged.String(), "bytes of application/pdf") {
+		t.Errorf("Expected the receipt to be summarized in the log:\n%s", logged.String())
+	}
+
+	err = tx.DownloadReceipt(10, &buf)
+	if !IsNotFound(err) {
+		t.Errorf("Expected a not found error, got %v", err)
+	}
+}
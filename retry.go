@@ -0,0 +1,174 @@
+package bento
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how a session retries requests that fail with a
+// connection error, 429 Too Many Requests or a 5xx status.
+//
+// Only GET, PUT and DELETE requests are retried. POST requests such as
+// NewCard and Reissue are never retried, since a request that timed out may
+// still have created a card.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first.
+	// Values below 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. Each later delay
+	// is multiplied by Multiplier, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Jitter randomizes each delay by up to this fraction of it, so that
+	// many clients failing at once don't retry in lockstep. 0.2 means
+	// +/-20%.
+	Jitter float64
+	// MaxRetryAfter is the longest delay asked for by a Retry-After header
+	// that the session will wait out. If the API asks for longer, the
+	// response is returned to the caller instead of being retried. Zero
+	// means MaxBackoff, or DefaultMaxRetryAfter if that is zero too.
+	MaxRetryAfter time.Duration
+}
+
+// DefaultMaxRetryAfter is the longest Retry-After delay waited out under a
+// policy that sets neither MaxRetryAfter nor MaxBackoff.
+const DefaultMaxRetryAfter = time.Minute
+
+// DefaultRetryPolicy is used by sessions unless WithRetryPolicy is given.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 250 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+	MaxRetryAfter:  30 * time.Second,
+}
+
+// NoRetries disables retries.
+var NoRetries = RetryPolicy{MaxAttempts: 1}
+
+// WithRetryPolicy sets the session's retry policy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(session *Session) {
+		session.retryPolicy = policy
+	}
+}
+
+// backoff returns the delay before retry number n, counting from 1.
+func (policy RetryPolicy) backoff(n int) time.Duration {
+	delay := float64(policy.InitialBackoff)
+	for i := 1; i < n; i++ {
+		if policy.Multiplier > 0 {
+			delay *= policy.Multiplier
+		}
+		if policy.MaxBackoff > 0 && delay > float64(policy.MaxBackoff) {
+			delay = float64(policy.MaxBackoff)
+			break
+		}
+	}
+	if policy.Jitter > 0 {
+		delay += delay * policy.Jitter * (2*rand.Float64() - 1)
+	}
+	if policy.MaxBackoff > 0 && delay > float64(policy.MaxBackoff) {
+		delay = float64(policy.MaxBackoff)
+	}
+	return time.Duration(delay)
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// maxRetryAfter returns the longest Retry-After delay the policy waits out.
+func (policy RetryPolicy) maxRetryAfter() time.Duration {
+	if policy.MaxRetryAfter > 0 {
+		return policy.MaxRetryAfter
+	}
+	if policy.MaxBackoff > 0 {
+		return policy.MaxBackoff
+	}
+	return DefaultMaxRetryAfter
+}
+
+// retryableStatus reports whether a response with the given status is worth
+// retrying.
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// retryAfter parses a Retry-After header, which is either a number of
+// seconds or an HTTP date. It returns 0 if the header is absent or invalid.
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+// roundTripWithRetry is roundTrip, retried according to the session's
+// policy.
+func (session *Session) roundTripWithRetry(ctx context.Context, method, endpoint, contentType string, bs []byte) (*http.Response, []byte, error) {
+	policy := session.retryPolicy
+	for attempt := 1; ; attempt++ {
+		resp, body, err := session.roundTrip(ctx, method, endpoint, contentType, bs, attempt)
+		if attempt >= policy.MaxAttempts || !idempotent(method) || ctx.Err() != nil {
+			return resp, body, err
+		}
+
+		var delay time.Duration
+		if err == nil {
+			if !retryableStatus(resp.StatusCode) {
+				return resp, body, nil
+			}
+			delay = retryAfter(resp)
+			if delay > policy.maxRetryAfter() {
+				return resp, body, nil
+			}
+		}
+		if delay == 0 {
+			delay = policy.backoff(attempt)
+		}
+		session.log(ctx, slog.LevelWarn, "Retrying request",
+			slog.String("method", method),
+			slog.String("endpoint", endpoint),
+			slog.Int("attempt", attempt+1),
+			slog.Duration("delay", delay))
+
+		err = sleep(ctx, delay)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
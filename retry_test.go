@@ -0,0 +1,169 @@
+package bento
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+var fastRetries = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: time.Millisecond,
+	MaxBackoff:     5 * time.Millisecond,
+	Multiplier:     2,
+}
+
+// newFlakyServer returns a server that answers the first failures requests
+// with 503 and then serves SampleCard.
+func newFlakyServer(failures int) (*httptest.Server, *int) {
+	attempts := new(int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*attempts++
+		if *attempts <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("<html>503 Service Unavailable</html>"))
+			return
+		}
+		w.Write([]byte(SampleCard))
+	}))
+	return server, attempts
+}
+
+func newRetrySession(uri string, policy RetryPolicy) *Session {
+	return &Session{
+		apiUri:      uri,
+		retryPolicy: policy,
+		requester:   doRequest,
+	}
+}
+
+func TestRetryIdempotent(t *testing.T) {
+	t.Log("TestRetryIdempotent")
+	server, attempts := newFlakyServer(2)
+	defer server.Close()
+
+	session := newRetrySession(server.URL, fastRetries)
+	_, err := session.GetCard(12345)
+	if err != nil {
+		t.Fatalf("Expected GetCard to succeed after retries: %s", err)
+	}
+	if *attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", *attempts)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	t.Log("TestRetryGivesUp")
+	server, attempts := newFlakyServer(10)
+	defer server.Close()
+
+	session := newRetrySession(server.URL, fastRetries)
+	_, err := session.GetCard(12345)
+	if err == nil {
+		t.Error("Expected GetCard to fail")
+	}
+	if *attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", *attempts)
+	}
+}
+
+func TestRetryNeverRetriesPost(t *testing.T) {
+	t.Log("TestRetryNeverRetriesPost")
+	server, attempts := newFlakyServer(1)
+	defer server.Close()
+
+	session := newRetrySession(server.URL, fastRetries)
+	_, err := session.NewCard(EMPLOYEE_CARD, "Testing Card")
+	if err == nil {
+		t.Error("Expected NewCard to fail")
+	}
+	if *attempts != 1 {
+		t.Errorf("Expected POST /cards to be attempted once, got %d", *attempts)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Log("TestRetryAfter")
+	resp := &http.Response{Header: http.Header{}}
+	if d := retryAfter(resp); d != 0 {
+		t.Errorf("Expected 0 without Retry-After, got %s", d)
+	}
+
+	resp.Header.Set("Retry-After", "7")
+	if d := retryAfter(resp); d != 7*time.Second {
+		t.Errorf("Expected 7s, got %s", d)
+	}
+
+	resp.Header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	if d := retryAfter(resp); d < 50*time.Second || d > time.Minute {
+		t.Errorf("Expected about a minute, got %s", d)
+	}
+
+	resp.Header.Set("Retry-After", "soon")
+	if d := retryAfter(resp); d != 0 {
+		t.Errorf("Expected 0 for an invalid Retry-After, got %s", d)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	t.Log("TestBackoff")
+	policy := RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+	for n, expected := range []time.Duration{100, 200, 400, 800, 1000} {
+		expected *= time.Millisecond
+		d := policy.backoff(n + 1)
+		if d < expected*8/10 || d > expected*12/10 || d > time.Second {
+			t.Errorf("Retry %d: expected about %s, got %s", n+1, expected, d)
+		}
+	}
+}
+
+func TestRetryAfterTooLong(t *testing.T) {
+	t.Log("TestRetryAfterTooLong")
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.Header().Set("Retry-After", "86400")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	for _, policy := range []RetryPolicy{
+		fastRetries,
+		{MaxAttempts: 3, MaxRetryAfter: time.Hour},
+		{MaxAttempts: 3},
+	} {
+		attempts = 0
+		session := newRetrySession(server.URL, policy)
+		start := time.Now()
+		_, err := session.GetCard(12345)
+		if !IsRateLimited(err) {
+			t.Errorf("Expected the 429 to be returned, got: %v", err)
+		}
+		if attempts != 1 || time.Since(start) > time.Second {
+			t.Errorf("Expected no retry for a day long Retry-After, got %d attempts in %s", attempts, time.Since(start))
+		}
+	}
+}
+
+func TestMaxRetryAfter(t *testing.T) {
+	t.Log("TestMaxRetryAfter")
+	cases := []struct {
+		policy   RetryPolicy
+		expected time.Duration
+	}{
+		{RetryPolicy{MaxRetryAfter: time.Hour, MaxBackoff: time.Second}, time.Hour},
+		{RetryPolicy{MaxBackoff: time.Second}, time.Second},
+		{RetryPolicy{}, DefaultMaxRetryAfter},
+	}
+	for _, c := range cases {
+		if max := c.policy.maxRetryAfter(); max != c.expected {
+			t.Errorf("Expected %s for %+v, got %s", c.expected, c.policy, max)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+package bento
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultPageSize is the page size used when PageOptions.Size is zero.
+const DefaultPageSize = 50
+
+// PageOptions selects a page of results.
+type PageOptions struct {
+	// Offset is the index of the first result to return.
+	Offset int
+	// Size is the maximum number of results to return. Zero means
+	// DefaultPageSize.
+	Size int
+}
+
+func (opts PageOptions) size() int {
+	if opts.Size <= 0 {
+		return DefaultPageSize
+	}
+	return opts.Size
+}
+
+// values returns opts as the offset and size query parameters.
+func (opts PageOptions) values() url.Values {
+	values := url.Values{}
+	values.Set("offset", strconv.Itoa(opts.Offset))
+	values.Set("size", strconv.Itoa(opts.size()))
+	return values
+}
+
+// TransactionQuery selects transactions. Zero-valued fields don't filter.
+//
+// Date ranges, card IDs and category IDs are sent to the API as the
+// dateStart, dateEnd, cardIds and categoryIds query parameters. Every
+// filter, including those, is also applied to the transactions the API
+// returns, so pages may hold fewer than Size transactions.
+type TransactionQuery struct {
+	PageOptions
+
+	// TransactionDateFrom and TransactionDateTo bound TransactionDate,
+	// inclusive, and SettlementDateFrom and SettlementDateTo bound
+	// SettlementDate. Dates are in milliseconds, as in Transaction.
+	TransactionDateFrom int64
+	TransactionDateTo   int64
+	SettlementDateFrom  int64
+	SettlementDateTo    int64
+
+	// CardIds, CategoryIds, Statuses and Types each match transactions
+	// with any one of the given values.
+	CardIds     []int64
+	CategoryIds []int64
+	Statuses    []string
+	Types       []string
+
+	// Tags matches transactions carrying all of the given tags.
+	Tags []string
+	// PayeeName matches transactions whose payee name contains it,
+	// ignoring case.
+	PayeeName string
+
+	// MinAmount and MaxAmount bound Transaction.Amount, inclusive.
+	MinAmount *float64
+	MaxAmount *float64
+}
+
+// values returns the query parameters sent to the API for query.
+func (query TransactionQuery) values() url.Values {
+	values := query.PageOptions.values()
+	if query.TransactionDateFrom != 0 {
+		values.Set("dateStart", strconv.FormatInt(query.TransactionDateFrom, 10))
+	}
+	if query.TransactionDateTo != 0 {
+		values.Set("dateEnd", strconv.FormatInt(query.TransactionDateTo, 10))
+	}
+	if len(query.CardIds) > 0 {
+		values.Set("cardIds", joinIds(query.CardIds))
+	}
+	if len(query.CategoryIds) > 0 {
+		values.Set("categoryIds", joinIds(query.CategoryIds))
+	}
+	return values
+}
+
+func joinIds(ids []int64) string {
+	strs := make([]string, len(ids))
+	for i, id := range ids {
+		strs[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(strs, ",")
+}
+
+// Matches reports whether tx satisfies every filter in query.
+func (query TransactionQuery) Matches(tx Transaction) bool {
+	if !inRange(tx.TransactionDate, query.TransactionDateFrom, query.TransactionDateTo) ||
+		!inRange(tx.SettlementDate, query.SettlementDateFrom, query.SettlementDateTo) {
+		return false
+	}
+	if len(query.CardIds) > 0 && (tx.Card == nil || !slices.Contains(query.CardIds, tx.Card.CardId)) {
+		return false
+	}
+	if len(query.CategoryIds) > 0 &&
+		(tx.Category == nil || !slices.Contains(query.CategoryIds, tx.Category.TransactionCategoryId)) {
+		return false
+	}
+	if len(query.Statuses) > 0 && !slices.Contains(query.Statuses, tx.Status) {
+		return false
+	}
+	if len(query.Types) > 0 && !slices.Contains(query.Types, tx.Type) {
+		return false
+	}
+	for _, tag := range query.Tags {
+		if !slices.Contains(tx.Tags, tag) {
+			return false
+		}
+	}
+	if query.PayeeName != "" &&
+		(tx.Payee == nil || !strings.Contains(strings.ToLower(tx.Payee.Name), strings.ToLower(query.PayeeName))) {
+		return false
+	}
+	if query.MinAmount != nil && tx.Amount < *query.MinAmount {
+		return false
+	}
+	if query.MaxAmount != nil && tx.Amount > *query.MaxAmount {
+		return false
+	}
+	return true
+}
+
+func inRange(value, from, to int64) bool {
+	return (from == 0 || value >= from) && (to == 0 || value <= to)
+}
+
+// GetTransactionsPage returns one page of transactions.
+func (session *Session) GetTransactionsPage(opts PageOptions) (*Transactions, error) {
+	return session.GetTransactionsPageContext(context.Background(), opts)
+}
+
+// GetTransactionsPageContext is like GetTransactionsPage but uses ctx for
+// the request.
+func (session *Session) GetTransactionsPageContext(ctx context.Context, opts PageOptions) (*Transactions, error) {
+	return session.QueryTransactionsContext(ctx, TransactionQuery{PageOptions: opts})
+}
+
+// QueryTransactions returns the transactions in one page of results that
+// match query. Size and Amount are as reported by the API, before
+// client-side filtering.
+func (session *Session) QueryTransactions(query TransactionQuery) (*Transactions, error) {
+	return session.QueryTransactionsContext(context.Background(), query)
+}
+
+// QueryTransactionsContext is like QueryTransactions but uses ctx for the
+// request.
+func (session *Session) QueryTransactionsContext(ctx context.Context, query TransactionQuery) (*Transactions, error) {
+	page, err := session.queryTransactionsPage(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	page.CardTransactions = filterTransactions(page.CardTransactions, query)
+	return page, nil
+}
+
+// queryTransactionsPage fetches a page for query without filtering it.
+func (session *Session) queryTransactionsPage(ctx context.Context, query TransactionQuery) (*Transactions, error) {
+	bs, err := session.call(ctx, "GET", "/transactions?"+query.values().Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var transactions Transactions
+	err = json.Unmarshal(bs, &transactions)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions.bind(session)
+	return &transactions, nil
+}
+
+// bind binds each transaction and its card to session, so that their
+// methods can be called.
+func (transactions *Transactions) bind(session *Session) {
+	for i := range transactions.CardTransactions {
+		transactions.CardTransactions[i].bind(session)
+	}
+}
+
+func (transaction *Transaction) bind(session *Session) {
+	transaction.session = session
+	if transaction.Card != nil {
+		transaction.Card.session = session
+	}
+}
+
+func filterTransactions(transactions []Transaction, query TransactionQuery) []Transaction {
+	filtered := transactions[:0]
+	for _, tx := range transactions {
+		if query.Matches(tx) {
+			filtered = append(filtered, tx)
+		}
+	}
+	return filtered
+}
+
+// AllTransactions returns an iterator over every transaction, fetching
+// pages of pageSize transactions as they are needed. A pageSize of zero
+// means DefaultPageSize.
+//
+// If fetching a page fails, including because ctx is done, the iterator
+// yields the error once and stops:
+//
+//	for tx, err := range session.AllTransactions(ctx, 100) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+func (session *Session) AllTransactions(ctx context.Context, pageSize int) iter.Seq2[Transaction, error] {
+	return session.QueryAllTransactions(ctx, TransactionQuery{PageOptions: PageOptions{Size: pageSize}})
+}
+
+// QueryAllTransactions is like AllTransactions, but only yields
+// transactions matching query, starting from query.Offset and fetching pages
+// of query.Size.
+func (session *Session) QueryAllTransactions(ctx context.Context, query TransactionQuery) iter.Seq2[Transaction, error] {
+	return func(yield func(Transaction, error) bool) {
+		query := query
+		query.Size = query.size()
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(Transaction{}, err)
+				return
+			}
+			page, err := session.queryTransactionsPage(ctx, query)
+			if err != nil {
+				yield(Transaction{}, err)
+				return
+			}
+			for _, tx := range page.CardTransactions {
+				if query.Matches(tx) && !yield(tx, nil) {
+					return
+				}
+			}
+
+			// The API may return fewer transactions than asked for, so a
+			// short page only means the end if there is no total to go by.
+			query.Offset += len(page.CardTransactions)
+			if len(page.CardTransactions) == 0 ||
+				(page.Size > 0 && query.Offset >= page.Size) ||
+				(page.Size == 0 && len(page.CardTransactions) < query.Size) {
+				return
+			}
+		}
+	}
+}
+
+// GetTransaction returns the transaction with the given ID.
+func (session *Session) GetTransaction(transactionId int64) (*Transaction, error) {
+	return session.GetTransactionContext(context.Background(), transactionId)
+}
+
+// GetTransactionContext is like GetTransaction but uses ctx for the request.
+func (session *Session) GetTransactionContext(ctx context.Context, transactionId int64) (*Transaction, error) {
+	bs, err := session.call(ctx, "GET", fmt.Sprintf("/transactions/%d", transactionId), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var transaction Transaction
+	err = json.Unmarshal(bs, &transaction)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction.bind(session)
+	return &transaction, nil
+}
+
+// transactionAnnotations are the fields of a transaction that can be
+// updated. Both are always sent, so that they can be cleared.
+type transactionAnnotations struct {
+	Note string   `json:"note"`
+	Tags []string `json:"tags"`
+}
+
+// Put saves the transaction's Note and Tags, the only fields of a
+// transaction that can be changed, and returns the updated transaction.
+// Set Note to "" or Tags to nil to clear them.
+func (transaction *Transaction) Put() (*Transaction, error) {
+	return transaction.PutContext(context.Background())
+}
+
+// PutContext is like Put but uses ctx for the request.
+func (transaction *Transaction) PutContext(ctx context.Context) (*Transaction, error) {
+	annotations := transactionAnnotations{Note: transaction.Note, Tags: transaction.Tags}
+	if annotations.Tags == nil {
+		annotations.Tags = []string{}
+	}
+	endpoint := fmt.Sprintf("/transactions/%d", transaction.CardTransactionId)
+	bs, err := transaction.session.call(ctx, "PUT", endpoint, annotations)
+	if err != nil {
+		return nil, err
+	}
+
+	var transactionResp Transaction
+	err = json.Unmarshal(bs, &transactionResp)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionResp.bind(transaction.session)
+	return &transactionResp, nil
+}
+
+// Transactions returns the transactions in one page of the card's
+// transactions that match query. query.CardIds is ignored.
+func (card *Card) Transactions(query TransactionQuery) (*Transactions, error) {
+	return card.TransactionsContext(context.Background(), query)
+}
+
+// TransactionsContext is like Transactions but uses ctx for the request.
+func (card *Card) TransactionsContext(ctx context.Context, query TransactionQuery) (*Transactions, error) {
+	return card.session.QueryTransactionsContext(ctx, card.query(query))
+}
+
+// AllTransactions is like Session.QueryAllTransactions, but only yields the
+// card's transactions. query.CardIds is ignored.
+func (card *Card) AllTransactions(ctx context.Context, query TransactionQuery) iter.Seq2[Transaction, error] {
+	return card.session.QueryAllTransactions(ctx, card.query(query))
+}
+
+func (card *Card) query(query TransactionQuery) TransactionQuery {
+	query.CardIds = []int64{card.CardId}
+	return query
+}
@@ -0,0 +1,338 @@
+package bento
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// pagingRequest serves count transactions from GET /transactions, honoring
+// the offset and size parameters but no filters. Transaction i has an amount
+// of i. It records each endpoint requested.
+func pagingRequest(count int, endpoints *[]string) requestFunc {
+	return func(ctx context.Context, session *Session, method, endpoint string, args interface{}) ([]byte, error) {
+		*endpoints = append(*endpoints, endpoint)
+		path, rawQuery, _ := strings.Cut(endpoint, "?")
+		if method != "GET" || path != "/transactions" {
+			return nil, errors.New("No such testing endpoint.")
+		}
+		query, err := url.ParseQuery(rawQuery)
+		if err != nil {
+			return nil, err
+		}
+		offset, _ := strconv.Atoi(query.Get("offset"))
+		size, _ := strconv.Atoi(query.Get("size"))
+
+		page := Transactions{Size: count}
+		for i := offset; i < offset+size && i < count; i++ {
+			page.CardTransactions = append(page.CardTransactions, Transaction{CardTransactionId: int64(i), Amount: float64(i)})
+		}
+		return json.Marshal(page)
+	}
+}
+
+func TestGetTransactionsPage(t *testing.T) {
+	t.Log("TestGetTransactionsPage")
+	var endpoints []string
+	session := &Session{requester: pagingRequest(10, &endpoints)}
+
+	page, err := session.GetTransactionsPage(PageOptions{Offset: 8, Size: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Size != 10 || len(page.CardTransactions) != 2 || page.CardTransactions[0].CardTransactionId != 8 {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+	if endpoints[0] != "/transactions?offset=8&size=5" {
+		t.Errorf("Unexpected endpoint: %s", endpoints[0])
+	}
+
+	if _, err := session.GetTransactionsPage(PageOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if endpoints[1] != "/transactions?offset=0&size=50" {
+		t.Errorf("Expected the default page size, got endpoint: %s", endpoints[1])
+	}
+}
+
+func TestAllTransactions(t *testing.T) {
+	t.Log("TestAllTransactions")
+	var endpoints []string
+	session := &Session{requester: pagingRequest(7, &endpoints)}
+
+	var ids []int64
+	for tx, err := range session.AllTransactions(context.Background(), 3) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, tx.CardTransactionId)
+	}
+	if len(ids) != 7 || ids[6] != 6 {
+		t.Errorf("Expected transactions 0 through 6, got %v", ids)
+	}
+	if len(endpoints) != 3 {
+		t.Errorf("Expected 3 pages to be fetched, got %v", endpoints)
+	}
+
+	// Exactly full pages end when the total is reached.
+	endpoints = nil
+	session.requester = pagingRequest(6, &endpoints)
+	for _, err := range session.AllTransactions(context.Background(), 3) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(endpoints) != 2 {
+		t.Errorf("Expected 2 pages to be fetched, got %v", endpoints)
+	}
+}
+
+func TestAllTransactionsLazy(t *testing.T) {
+	t.Log("TestAllTransactionsLazy")
+	var endpoints []string
+	session := &Session{requester: pagingRequest(100, &endpoints)}
+
+	count := 0
+	for range session.AllTransactions(context.Background(), 10) {
+		count++
+		if count == 15 {
+			break
+		}
+	}
+	if len(endpoints) != 2 {
+		t.Errorf("Expected only 2 pages to be fetched, got %v", endpoints)
+	}
+}
+
+func TestAllTransactionsCanceled(t *testing.T) {
+	t.Log("TestAllTransactionsCanceled")
+	var endpoints []string
+	session := &Session{requester: pagingRequest(100, &endpoints)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	count := 0
+	var lastErr error
+	for _, err := range session.AllTransactions(ctx, 10) {
+		if err != nil {
+			lastErr = err
+			break
+		}
+		count++
+		if count == 10 {
+			cancel()
+		}
+	}
+	if !errors.Is(lastErr, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got: %v", lastErr)
+	}
+	if count != 10 || len(endpoints) != 1 {
+		t.Errorf("Expected to stop after the first page, got %d transactions from %v", count, endpoints)
+	}
+}
+
+func TestAllTransactionsError(t *testing.T) {
+	t.Log("TestAllTransactionsError")
+	session := &Session{requester: testRequestFailures(nil)}
+
+	errs := 0
+	for _, err := range session.AllTransactions(context.Background(), 10) {
+		if err == nil {
+			t.Error("Expected only an error")
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Errorf("Expected the error to be yielded once, got %d", errs)
+	}
+}
+
+func TestTransactionQueryValues(t *testing.T) {
+	t.Log("TestTransactionQueryValues")
+	query := TransactionQuery{
+		PageOptions:         PageOptions{Offset: 10, Size: 20},
+		TransactionDateFrom: 1000,
+		TransactionDateTo:   2000,
+		SettlementDateFrom:  1500,
+		CardIds:             []int64{1, 2},
+		CategoryIds:         []int64{3},
+		Statuses:            []string{"SETTLED"},
+		PayeeName:           "coffee",
+	}
+	expected := "cardIds=1%2C2&categoryIds=3&dateEnd=2000&dateStart=1000&offset=10&size=20"
+	if values := query.values().Encode(); values != expected {
+		t.Errorf("Unexpected query parameters: %s", values)
+	}
+}
+
+func TestTransactionQueryMatches(t *testing.T) {
+	t.Log("TestTransactionQueryMatches")
+	low, high := 10.0, 20.0
+	tx := Transaction{
+		TransactionDate: 1000,
+		SettlementDate:  2000,
+		Card:            &Card{CardId: 7},
+		Category:        &Category{TransactionCategoryId: 3},
+		Status:          "SETTLED",
+		Type:            "DEBIT",
+		Tags:            []string{"travel", "q3"},
+		Payee:           &Payee{Name: "Blue Bottle Coffee"},
+		Amount:          15,
+	}
+
+	matching := []TransactionQuery{
+		{},
+		{TransactionDateFrom: 1000, TransactionDateTo: 1000},
+		{SettlementDateFrom: 1500},
+		{CardIds: []int64{6, 7}},
+		{CategoryIds: []int64{3}},
+		{Statuses: []string{"PENDING", "SETTLED"}},
+		{Types: []string{"DEBIT"}},
+		{Tags: []string{"q3", "travel"}},
+		{PayeeName: "coffee"},
+		{MinAmount: &low, MaxAmount: &high},
+	}
+	for _, query := range matching {
+		if !query.Matches(tx) {
+			t.Errorf("Expected %+v to match", query)
+		}
+	}
+
+	missing := []TransactionQuery{
+		{TransactionDateFrom: 1001},
+		{SettlementDateTo: 1999},
+		{CardIds: []int64{6}},
+		{CategoryIds: []int64{4}},
+		{Statuses: []string{"PENDING"}},
+		{Types: []string{"CREDIT"}},
+		{Tags: []string{"travel", "q4"}},
+		{PayeeName: "tea"},
+		{MinAmount: &high},
+		{MaxAmount: &low},
+	}
+	for _, query := range missing {
+		if query.Matches(tx) {
+			t.Errorf("Expected %+v not to match", query)
+		}
+	}
+
+	if (TransactionQuery{CardIds: []int64{7}}).Matches(Transaction{}) {
+		t.Error(`Expected a transaction without a card not to match a card filter`)
+	}
+}
+
+func TestQueryAllTransactionsFiltersClientSide(t *testing.T) {
+	t.Log("TestQueryAllTransactionsFiltersClientSide")
+	var endpoints []string
+	session := &Session{requester: pagingRequest(10, &endpoints)}
+
+	// pagingRequest ignores filters, so every match is made client-side.
+	min := 4.0
+	query := TransactionQuery{PageOptions: PageOptions{Size: 3}, MinAmount: &min}
+	page, err := session.QueryTransactions(query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.CardTransactions) != 0 || page.Size != 10 {
+		t.Errorf("Expected an empty page of 10, got %+v", page)
+	}
+
+	// Pages that filter down to nothing don't end the iteration early.
+	endpoints = nil
+	var ids []int64
+	for tx, err := range session.QueryAllTransactions(context.Background(), query) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, tx.CardTransactionId)
+	}
+	if len(ids) != 6 || ids[0] != 4 {
+		t.Errorf("Expected transactions 4 through 9, got %v", ids)
+	}
+	if len(endpoints) != 4 {
+		t.Errorf("Expected every page to be fetched, got %v", endpoints)
+	}
+}
+
+func TestCardTransactions(t *testing.T) {
+	t.Log("TestCardTransactions")
+	var endpoints []string
+	session := &Session{requester: pagingRequest(10, &endpoints)}
+	card := &Card{CardId: 42, session: session}
+
+	_, err := card.Transactions(TransactionQuery{PageOptions: PageOptions{Size: 5}, CardIds: []int64{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoints[0] != "/transactions?cardIds=42&offset=0&size=5" {
+		t.Errorf("Unexpected endpoint: %s", endpoints[0])
+	}
+}
+
+func TestTransactionCardBound(t *testing.T) {
+	t.Log("TestTransactionCardBound")
+	session := &Session{requester: func(ctx context.Context, session *Session, method, endpoint string, args interface{}) ([]byte, error) {
+		return []byte(`{"cardTransactions": [{"cardTransactionId": 1, "card": {"cardId": 42}}], "size": 1}`), nil
+	}}
+
+	page, err := session.GetTransactions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.CardTransactions[0].Card.session != session {
+		t.Error(`Expected GetTransactions to bind cards to the session`)
+	}
+
+	page, err = session.QueryTransactions(TransactionQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.CardTransactions[0].Card.session != session {
+		t.Error(`Expected QueryTransactions to bind cards to the session`)
+	}
+}
+
+func TestTransactionNoteJSON(t *testing.T) {
+	t.Log("TestTransactionNoteJSON")
+	var tx Transaction
+	err := json.Unmarshal([]byte(`{"cardTransactionId": 1, "note": "Team offsite"}`), &tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tx.Note != "Team offsite" {
+		t.Errorf(`Expected the note to be decoded, got %q`, tx.Note)
+	}
+	bs, _ := json.Marshal(tx)
+	if !strings.Contains(string(bs), `"note":"Team offsite"`) {
+		t.Errorf("Expected the note to be encoded, got %s", bs)
+	}
+}
+
+func TestTransactionPut(t *testing.T) {
+	t.Log("TestTransactionPut")
+	var body string
+	session := &Session{requester: func(ctx context.Context, session *Session, method, endpoint string, args interface{}) ([]byte, error) {
+		if method != "PUT" || endpoint != "/transactions/7" {
+			return nil, errors.New("No such testing endpoint.")
+		}
+		bs, _ := json.Marshal(args)
+		body = string(bs)
+		return []byte(`{"cardTransactionId": 7}`), nil
+	}}
+
+	tx := &Transaction{CardTransactionId: 7, Amount: 10, session: session}
+	updated, err := tx.Put()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != `{"note":"","tags":[]}` {
+		t.Errorf("Expected only the annotations to be sent, got %s", body)
+	}
+	if updated.session != session {
+		t.Error(`Expected the updated transaction to be bound to the session`)
+	}
+}
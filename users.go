@@ -0,0 +1,154 @@
+package bento
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// GetUsers returns the business's users.
+func (session *Session) GetUsers() ([]User, error) {
+	return session.GetUsersContext(context.Background())
+}
+
+// GetUsersContext is like GetUsers but uses ctx for the request.
+func (session *Session) GetUsersContext(ctx context.Context) ([]User, error) {
+	bs, err := session.call(ctx, "GET", "/users", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var users []User
+	err = json.Unmarshal(bs, &users)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range users {
+		users[i].session = session
+	}
+
+	return users, nil
+}
+
+// GetUser returns the user with the given ID.
+func (session *Session) GetUser(userId int64) (*User, error) {
+	return session.GetUserContext(context.Background(), userId)
+}
+
+// GetUserContext is like GetUser but uses ctx for the request.
+func (session *Session) GetUserContext(ctx context.Context, userId int64) (*User, error) {
+	bs, err := session.call(ctx, "GET", fmt.Sprintf("/users/%d", userId), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var user User
+	err = json.Unmarshal(bs, &user)
+	if err != nil {
+		return nil, err
+	}
+
+	user.session = session
+	return &user, nil
+}
+
+// NewUser creates a user from the given details and returns it. UserId,
+// Created and Deleted are ignored.
+func (session *Session) NewUser(user User) (*User, error) {
+	return session.NewUserContext(context.Background(), user)
+}
+
+// NewUserContext is like NewUser but uses ctx for the request.
+func (session *Session) NewUserContext(ctx context.Context, user User) (*User, error) {
+	user.UserId = 0
+	user.Created = 0
+	user.Deleted = false
+	bs, err := session.call(ctx, "POST", "/users", user)
+	if err != nil {
+		return nil, err
+	}
+
+	var userResp User
+	err = json.Unmarshal(bs, &userResp)
+	if err != nil {
+		return nil, err
+	}
+
+	userResp.session = session
+	return &userResp, nil
+}
+
+// Put saves the user's details and returns the updated user.
+func (user *User) Put() (*User, error) {
+	return user.PutContext(context.Background())
+}
+
+// PutContext is like Put but uses ctx for the request.
+func (user *User) PutContext(ctx context.Context) (*User, error) {
+	bs, err := user.session.call(ctx, "PUT", fmt.Sprintf("/users/%d", user.UserId), user)
+	if err != nil {
+		return nil, err
+	}
+
+	var userResp User
+	err = json.Unmarshal(bs, &userResp)
+	if err != nil {
+		return nil, err
+	}
+
+	userResp.session = user.session
+	return &userResp, nil
+}
+
+// Delete removes the user from the business and returns it.
+func (user *User) Delete() (*User, error) {
+	return user.DeleteContext(context.Background())
+}
+
+// DeleteContext is like Delete but uses ctx for the request.
+func (user *User) DeleteContext(ctx context.Context) (*User, error) {
+	bs, err := user.session.call(ctx, "DELETE", fmt.Sprintf("/users/%d", user.UserId), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var userResp User
+	err = json.Unmarshal(bs, &userResp)
+	if err != nil {
+		return nil, err
+	}
+
+	userResp.session = user.session
+	return &userResp, nil
+}
+
+// userAssignment is the body of a request that changes a card's user.
+type userAssignment struct {
+	User userRef `json:"user"`
+}
+
+// AssignCard makes the user the holder of card, and returns the updated
+// card. Only the card's user is changed; other unsaved changes to card are
+// not sent.
+func (user *User) AssignCard(card *Card) (*Card, error) {
+	return user.AssignCardContext(context.Background(), card)
+}
+
+// AssignCardContext is like AssignCard but uses ctx for the request.
+func (user *User) AssignCardContext(ctx context.Context, card *Card) (*Card, error) {
+	assignment := userAssignment{User: userRef{UserId: user.UserId}}
+	bs, err := user.session.call(ctx, "PUT", fmt.Sprintf("/cards/%d", card.CardId), assignment)
+	if err != nil {
+		return nil, err
+	}
+
+	var cardResp Card
+	err = json.Unmarshal(bs, &cardResp)
+	if err != nil {
+		return nil, err
+	}
+
+	cardResp.session = user.session
+	return &cardResp, nil
+}
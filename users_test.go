@@ -0,0 +1,131 @@
+package bento
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+var SampleUser string = `{
+  "firstName": "John",
+  "lastName": "Smith",
+  "email": "me@myemail.com",
+  "userId": 12345,
+  "mobileAccess": true,
+  "deleted": false,
+  "created": 1495759408
+}`
+
+// userRequest answers the users endpoints with SampleUser, recording each
+// call in tbs.
+func userRequest(tbs *TestSession) requestFunc {
+	return func(ctx context.Context, session *Session, method, endpoint string, args interface{}) ([]byte, error) {
+		tbs.method = method
+		tbs.endpoint = endpoint
+		tbs.args = args
+		switch endpoint {
+		case "/users":
+			if method == "GET" {
+				return []byte("[" + SampleUser + "]"), nil
+			}
+			return []byte(SampleUser), nil
+		case "/users/12345":
+			return []byte(SampleUser), nil
+		case "/cards/12345":
+			return []byte(SampleCard), nil
+		}
+		return nil, errors.New("No such testing endpoint.")
+	}
+}
+
+func TestUsers(t *testing.T) {
+	t.Log("TestUsers")
+	tbs := &TestSession{}
+	session := &Session{requester: userRequest(tbs)}
+
+	users, err := session.GetUsers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].UserId != 12345 || users[0].session != session {
+		t.Errorf("Unexpected users: %+v", users)
+	}
+
+	user, err := session.NewUser(User{FirstName: "John", LastName: "Smith", Email: "me@myemail.com", UserId: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tbs.method != "POST" || tbs.endpoint != "/users" || tbs.args.(User).UserId != 0 {
+		t.Errorf("Unexpected request: %s %s %+v", tbs.method, tbs.endpoint, tbs.args)
+	}
+	if user.session != session {
+		t.Error(`Expected the new user to be bound to the session`)
+	}
+
+	user, err = session.GetUser(12345)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user.Phone = "5555550100"
+	_, err = user.Put()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tbs.method != "PUT" || tbs.endpoint != "/users/12345" || tbs.args.(*User).Phone != "5555550100" {
+		t.Errorf("Unexpected request: %s %s %+v", tbs.method, tbs.endpoint, tbs.args)
+	}
+
+	_, err = user.Delete()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tbs.method != "DELETE" || tbs.endpoint != "/users/12345" {
+		t.Errorf("Unexpected request: %s %s", tbs.method, tbs.endpoint)
+	}
+}
+
+func TestAssignCard(t *testing.T) {
+	t.Log("TestAssignCard")
+	tbs := &TestSession{}
+	session := &Session{requester: userRequest(tbs)}
+	user := &User{UserId: 777, session: session}
+	card := &Card{CardId: 12345, Alias: "Unsaved alias"}
+
+	updated, err := user.AssignCard(card)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, _ := json.Marshal(tbs.args)
+	if tbs.method != "PUT" || tbs.endpoint != "/cards/12345" || string(bs) != `{"user":{"userId":777}}` {
+		t.Errorf("Unexpected request: %s %s %s", tbs.method, tbs.endpoint, bs)
+	}
+	if updated.session != session {
+		t.Error(`Expected the card to be bound to the session`)
+	}
+}
+
+func TestUsersFailure(t *testing.T) {
+	t.Log("TestUsersFailure")
+	session := &Session{requester: testRequestFailures(nil)}
+
+	if _, err := session.GetUsers(); err == nil {
+		t.Error("Expected failure when calling GetUsers")
+	}
+	if _, err := session.GetUser(12345); err == nil {
+		t.Error("Expected failure when calling GetUser")
+	}
+	if _, err := session.NewUser(User{}); err == nil {
+		t.Error("Expected failure when calling NewUser")
+	}
+	user := &User{UserId: 12345, session: session}
+	if _, err := user.Put(); err == nil {
+		t.Error("Expected failure when calling User.Put")
+	}
+	if _, err := user.Delete(); err == nil {
+		t.Error("Expected failure when calling User.Delete")
+	}
+	if _, err := user.AssignCard(&Card{CardId: 12345}); err == nil {
+		t.Error("Expected failure when calling AssignCard")
+	}
+}